@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// asnRange is one row of an IP-to-ASN dataset: the inclusive address range
+// announced by an autonomous system.
+type asnRange struct {
+	start, end net.IP // 16-byte form so IPv4 and IPv6 compare uniformly
+	asn        uint32
+	org        string
+}
+
+// asnDB maps addresses to the AS announcing them.
+type asnDB struct {
+	ranges []asnRange // sorted by start
+}
+
+// loadASNDB reads a tab-separated IP-to-ASN dataset in the iptoasn.com
+// layout: range_start, range_end, AS number, country code, AS description.
+// Rows for unrouted space (AS 0) are skipped.
+func loadASNDB(path string) (*asnDB, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	db := &asnDB{}
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("%s:%d: expected at least 3 tab-separated fields", path, line)
+		}
+		start := net.ParseIP(fields[0]).To16()
+		end := net.ParseIP(fields[1]).To16()
+		asn, err := strconv.ParseUint(fields[2], 10, 32)
+		if start == nil || end == nil || err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid range or AS number", path, line)
+		}
+		if asn == 0 {
+			continue
+		}
+
+		entry := asnRange{start: start, end: end, asn: uint32(asn)}
+		if len(fields) >= 5 {
+			entry.org = fields[4]
+		}
+		db.ranges = append(db.ranges, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(db.ranges, func(i, j int) bool {
+		return bytes.Compare(db.ranges[i].start, db.ranges[j].start) < 0
+	})
+	return db, nil
+}
+
+// lookup returns the range containing ip.
+func (db *asnDB) lookup(ip net.IP) (asnRange, bool) {
+	ip = ip.To16()
+	if db == nil || ip == nil {
+		return asnRange{}, false
+	}
+
+	// Find the last range starting at or before ip.
+	i := sort.Search(len(db.ranges), func(i int) bool {
+		return bytes.Compare(db.ranges[i].start, ip) > 0
+	}) - 1
+	if i < 0 || bytes.Compare(ip, db.ranges[i].end) > 0 {
+		return asnRange{}, false
+	}
+	return db.ranges[i], true
+}
+
+// asnInfo picks the address reported for a subdomain with -with-asn: the
+// first resolved address with a known AS, or else the first address.
+func (s *SubHunter) asnInfo(subdomain string) (net.IP, asnRange, bool) {
+	ips := s.resolvedIPs(subdomain)
+	for _, ip := range ips {
+		if entry, ok := s.asnDB.lookup(ip); ok {
+			return ip, entry, true
+		}
+	}
+	if len(ips) > 0 {
+		return ips[0], asnRange{}, false
+	}
+	return nil, asnRange{}, false
+}
+
+// asnAnnotation formats the "IP ASxxx Org" suffix of a text result.
+func (s *SubHunter) asnAnnotation(subdomain string) string {
+	ip, entry, ok := s.asnInfo(subdomain)
+	if ip == nil {
+		return ""
+	}
+	if !ok {
+		return ip.String()
+	}
+	annotation := fmt.Sprintf("%s AS%d", ip, entry.asn)
+	if entry.org != "" {
+		annotation += " " + entry.org
+	}
+	return annotation
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// bloomFalsePositiveRate is the target false-positive rate of the -low-memory
+// filter. A false positive means a genuinely new subdomain is treated as a
+// duplicate and silently dropped, so the rate is kept deliberately small.
+const bloomFalsePositiveRate = 0.001
+
+// seenSet tracks which subdomains have already been reported.
+type seenSet interface {
+	// add records name and reports whether it had not been seen before.
+	add(name string) bool
+}
+
+type mapSet map[string]bool
+
+func (m mapSet) add(name string) bool {
+	if m[name] {
+		return false
+	}
+	m[name] = true
+	return true
+}
+
+// bloomFilter is a fixed-size probabilistic set. Memory use is bounded by the
+// configured capacity regardless of how many names are added, at the cost of
+// occasionally reporting an unseen name as already present.
+type bloomFilter struct {
+	bits []uint64
+	m    uint64
+	k    uint64
+}
+
+func newBloomFilter(capacity int, falsePositiveRate float64) *bloomFilter {
+	if capacity < 1 {
+		capacity = 1
+	}
+	n := float64(capacity)
+	m := uint64(math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Max(1, math.Round(float64(m)/n*math.Ln2)))
+
+	return &bloomFilter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+func (b *bloomFilter) add(name string) bool {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	h1 := h.Sum64()
+	h2 := h1>>33 | h1<<31 | 1
+
+	added := false
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if b.bits[word]&mask == 0 {
+			b.bits[word] |= mask
+			added = true
+		}
+	}
+	return added
+}
+
+func (s *SubHunter) newSeenSet() seenSet {
+	if s.lowMemory {
+		return newBloomFilter(s.bloomSize, bloomFalsePositiveRate)
+	}
+	return make(mapSet)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// caStatsTop is how many certificate authorities -ca-stats lists.
+const caStatsTop = 10
+
+// CACount is the number of matching certificates issued by one CA.
+type CACount struct {
+	CA           string `json:"ca"`
+	Certificates int    `json:"certificates"`
+}
+
+// issuerCA names the CA of a crt.sh issuer_name by its organization, so
+// that e.g. Let's Encrypt's R3 and E5 intermediates count as one CA. An
+// issuer without an organization is named as a whole.
+func issuerCA(issuer string) string {
+	var field strings.Builder
+	quoted := false
+	var fields []string
+	for _, r := range issuer {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteRune(r)
+		}
+	}
+	fields = append(fields, field.String())
+
+	for _, f := range fields {
+		if org, ok := strings.CutPrefix(strings.TrimSpace(f), "O="); ok && org != "" {
+			return org
+		}
+	}
+	return strings.TrimSpace(issuer)
+}
+
+// recordIssuer counts a matching certificate for its CA. Each certificate
+// is counted once even if several domains matched it.
+func (s *SubHunter) recordIssuer(result CRTResponse) {
+	ca := issuerCA(result.IssuerName)
+	if ca == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.caCounts == nil {
+		s.caCounts = make(map[string]int)
+		s.caSeen = make(map[int64]bool)
+	}
+	if result.ID > 0 {
+		if s.caSeen[result.ID] {
+			return
+		}
+		s.caSeen[result.ID] = true
+	}
+	s.caCounts[ca]++
+}
+
+// topCAs returns the n CAs that issued the most matching certificates.
+func (s *SubHunter) topCAs(n int) []CACount {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make([]CACount, 0, len(s.caCounts))
+	for ca, count := range s.caCounts {
+		counts = append(counts, CACount{CA: ca, Certificates: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Certificates != counts[j].Certificates {
+			return counts[i].Certificates > counts[j].Certificates
+		}
+		return counts[i].CA < counts[j].CA
+	})
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultTimeout is the HTTP timeout in seconds used when a command has no
+// -t flag of its own.
+const defaultTimeout = 60
+
+// commands maps subcommand names to their entry points. Running SubHunter
+// without a subcommand (e.g. "SubHunter -d example.com") runs enum.
+var commands = map[string]func(args []string){
+	"enum":     runEnum,
+	"diff":     runDiff,
+	"dedupe":   runDedupe,
+	"validate": runValidate,
+	"selftest": runSelftest,
+}
+
+const commandsUsage = `Usage: SubHunter [command] [flags]
+
+Commands:
+  enum     enumerate subdomains from certificate transparency (default)
+  diff     compare two result files
+  dedupe   merge and deduplicate result files
+  validate filter a subdomain list down to valid DNS names
+  selftest check connectivity to crt.sh, DNS and any proxy
+
+Run "SubHunter <command> -h" for the flags of a command.
+`
+
+func main() {
+	args := os.Args[1:]
+
+	if len(args) > 0 {
+		if run, ok := commands[args[0]]; ok {
+			run(args[1:])
+			return
+		}
+		if args[0] == "help" {
+			fmt.Print(commandsUsage)
+			return
+		}
+	}
+
+	run, args := legacyCommand(args)
+	run(args)
+}
+
+// legacyCommand maps the flag-style invocations that predate subcommands
+// ("-dedupe a.txt b.txt", "-diff old.txt new.txt", "-validate list.txt",
+// "-selftest") onto their subcommand.
+// Anything else is an enum invocation.
+func legacyCommand(args []string) (func([]string), []string) {
+	for i, arg := range args {
+		name := strings.TrimLeft(arg, "-")
+		if arg == name || (name != "dedupe" && name != "diff" && name != "validate" && name != "selftest") {
+			continue
+		}
+		rest := append(append([]string{}, args[:i]...), args[i+1:]...)
+		return commands[name], rest
+	}
+	return runEnum, args
+}
+
+// newFlagSet creates the flag set for a subcommand with usage output that
+// also lists the other commands.
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: SubHunter %s\n\nFlags:\n", usage)
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\n%s", commandsUsage)
+	}
+	return fs
+}
+
+// parseInterspersed parses args while allowing positional arguments to
+// appear between flags (e.g. "dedupe a.txt b.txt -o out.txt"). It returns
+// the positional arguments in order.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	fs.Parse(args)
+	for fs.NArg() > 0 {
+		positional = append(positional, fs.Arg(0))
+		fs.Parse(fs.Args()[1:])
+	}
+	return positional
+}
+
+// listFlag is a flag that may be repeated or given a comma-separated list;
+// every value is accumulated in order.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// repeatedFlag is a flag that may be given several times; each value is
+// kept as is.
+type repeatedFlag []string
+
+func (r *repeatedFlag) String() string {
+	return strings.Join(*r, ",")
+}
+
+func (r *repeatedFlag) Set(value string) error {
+	*r = append(*r, value)
+	return nil
+}
+
+func printBanner(silent bool) {
+	if !silent {
+		fmt.Printf("%s%s%s%s", pink, bold, fmt.Sprintf(banner, version), reset)
+	}
+}
+
+// formatOptions are the flags controlling how a result list is rendered.
+type formatOptions struct {
+	jsonOutput     *bool
+	pretty         *bool
+	urls           *bool
+	bothSchemes    *bool
+	ports          *string
+	sortMode       *string
+	outputEncoding *string
+	lineEnding     *string
+	fields         *string
+}
+
+func registerFormatFlags(fs *flag.FlagSet) *formatOptions {
+	return &formatOptions{
+		jsonOutput:     fs.Bool("json", false, "output results as JSON"),
+		pretty:         fs.Bool("pretty", false, "with -json, indent the output for readability"),
+		urls:           fs.Bool("urls", false, "output https:// URLs instead of bare subdomains"),
+		bothSchemes:    fs.Bool("both-schemes", false, "with -urls, also output http:// URLs"),
+		ports:          fs.String("ports", "", "with -urls, comma-separated ports to expand (e.g. 443,8443)"),
+		sortMode:       fs.String("sort", sortAlpha, "result ordering: alpha, regdomain (group by registrable domain) reverse-label (group by parent domain) or count (most certificates first)"),
+		outputEncoding: fs.String("output-encoding", encodingASCII, "hostname encoding for output: ascii (punycode) or unicode"),
+		lineEnding:     fs.String("line-ending", lineEndingLF, "line terminator for text output: lf, crlf or none (no newline after the last line)"),
+		fields:         fs.String("fields", "", "comma-separated fields to keep in JSON results, e.g. subdomain,first_seen (default: all populated fields)"),
+	}
+}
+
+// apply validates the format flags and configures the hunter with them.
+func (o *formatOptions) apply(hunter *SubHunter) error {
+	if *o.outputEncoding != encodingASCII && *o.outputEncoding != encodingUnicode {
+		return fmt.Errorf("unknown output encoding %q (expected ascii or unicode)", *o.outputEncoding)
+	}
+	if err := validateSortMode(*o.sortMode); err != nil {
+		return err
+	}
+	switch *o.lineEnding {
+	case lineEndingLF, lineEndingCRLF, lineEndingNone:
+	default:
+		return fmt.Errorf("unknown line ending %q (expected lf, crlf or none)", *o.lineEnding)
+	}
+
+	hunter.jsonOutput = *o.jsonOutput
+	hunter.pretty = *o.pretty
+	hunter.urls = *o.urls || *o.bothSchemes || *o.ports != ""
+	hunter.bothSchemes = *o.bothSchemes
+	hunter.sortMode = *o.sortMode
+	hunter.outputEncoding = *o.outputEncoding
+	hunter.lineEnding = *o.lineEnding
+
+	if *o.fields != "" {
+		fields, err := parseFields(*o.fields)
+		if err != nil {
+			return err
+		}
+		hunter.fields = fields
+	}
+
+	if *o.ports != "" {
+		ports, err := parsePorts(*o.ports)
+		if err != nil {
+			return err
+		}
+		hunter.ports = ports
+	}
+	return nil
+}
+
+// destinationOptions are the flags controlling where a result list goes.
+type destinationOptions struct {
+	outputs     repeatedFlag
+	outputURL   *string
+	merge       *bool
+	lockTimeout *time.Duration
+}
+
+func registerDestinationFlags(fs *flag.FlagSet) *destinationOptions {
+	o := &destinationOptions{
+		outputURL:   fs.String("output-url", "", "upload results with HTTP PUT to this http(s) URL instead of writing a local file"),
+		merge:       fs.Bool("merge", false, "union results into the existing -o file, locking it against concurrent runs"),
+		lockTimeout: fs.Duration("lock-timeout", 30*time.Second, "with -merge, how long to wait for another process holding the output lock"),
+	}
+	fs.Var(&o.outputs, "o", "output file path; repeat for several files, each in the format of its extension (.txt, .json, .csv)")
+	return o
+}
+
+// apply validates the destination flags, configures the hunter and returns
+// the output destinations (none for stdout).
+func (o *destinationOptions) apply(hunter *SubHunter) ([]string, error) {
+	outputs := o.outputs
+	if *o.outputURL != "" {
+		if len(outputs) > 0 {
+			return nil, fmt.Errorf("cannot use -o and -output-url together")
+		}
+		outputs = []string{*o.outputURL}
+	}
+
+	seen := make(map[string]bool)
+	for _, output := range outputs {
+		key := output
+		if !isRemoteOutput(output) {
+			key = filepath.Clean(output)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("output %s is given more than once", output)
+		}
+		seen[key] = true
+
+		if err := checkCompression(output); err != nil {
+			return nil, err
+		}
+		if *o.merge && isCompressedOutput(output) {
+			return nil, fmt.Errorf("cannot -merge into compressed output %s", output)
+		}
+	}
+
+	hunter.merge = *o.merge
+	hunter.lockTimeout = *o.lockTimeout
+	return outputs, nil
+}
+
+// exitOnError prints err in the standard error format and exits if it is
+// non-nil.
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Printf("%s[ERR]%s %s\n\n", pink, reset, err)
+		os.Exit(1)
+	}
+}
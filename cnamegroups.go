@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CNAMEGroup is a canonical name with the subdomains that resolve to it.
+// A subdomain without a CNAME is its own canonical name.
+type CNAMEGroup struct {
+	Target string   `json:"target"`
+	Hosts  []string `json:"hosts"`
+}
+
+// lookupCNAME returns the canonical name of host at the end of its CNAME
+// chain, without the trailing dot, or host itself if it has none or the
+// lookup fails.
+func (s *SubHunter) lookupCNAME(host string) string {
+	ctx := context.Background()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	s.acquireNet()
+	defer s.releaseNet()
+
+	cname, err := net.DefaultResolver.LookupCNAME(ctx, host)
+	if err != nil || cname == "" {
+		return host
+	}
+	return strings.TrimSuffix(strings.ToLower(cname), ".")
+}
+
+// cnameGroups resolves the CNAME of every subdomain with the worker pool
+// and groups the subdomains by canonical name, largest groups first.
+func (s *SubHunter) cnameGroups(subdomains []string) []CNAMEGroup {
+	s.log("run", fmt.Sprintf("Resolving CNAMEs of %d subdomains", len(subdomains)), "")
+
+	targets := make([]string, len(subdomains))
+	semaphore := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+	for i, sub := range subdomains {
+		wg.Add(1)
+		go func(idx int, host string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			targets[idx] = s.lookupCNAME(host)
+		}(i, sub)
+	}
+	wg.Wait()
+
+	hosts := make(map[string][]string)
+	for i, sub := range subdomains {
+		hosts[targets[i]] = append(hosts[targets[i]], sub)
+	}
+	groups := make([]CNAMEGroup, 0, len(hosts))
+	for target, members := range hosts {
+		groups = append(groups, CNAMEGroup{Target: target, Hosts: members})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if len(groups[i].Hosts) != len(groups[j].Hosts) {
+			return len(groups[i].Hosts) > len(groups[j].Hosts)
+		}
+		return groups[i].Target < groups[j].Target
+	})
+	return groups
+}
+
+// printCNAMEGroups prints each canonical name with the subdomains pointing
+// at it nested under it.
+func (s *SubHunter) printCNAMEGroups(subdomains []string) {
+	groups := s.cnameGroups(subdomains)
+
+	if s.jsonOutput {
+		encoder := json.NewEncoder(stdout)
+		if s.pretty {
+			encoder.SetIndent("", "  ")
+		}
+		encoder.Encode(map[string][]CNAMEGroup{"cname_groups": groups})
+		return
+	}
+	if s.silent {
+		return
+	}
+
+	fmt.Fprintf(stdout, "\n%s%s%s\n", pink, strings.Repeat("━", 60), reset)
+	fmt.Fprintf(stdout, "%s%s[CNAME GROUPS]%s\n", pink, bold, reset)
+	fmt.Fprintf(stdout, "%s%s%s\n", pink, strings.Repeat("━", 60), reset)
+	if len(groups) == 0 {
+		fmt.Fprintf(stdout, "  No subdomains to group\n")
+	}
+	for _, group := range groups {
+		fmt.Fprintf(stdout, "  %s%s%s (%d)\n", pink, group.Target, reset, len(group.Hosts))
+		for _, host := range group.Hosts {
+			fmt.Fprintf(stdout, "    └─ %s\n", host)
+		}
+	}
+}
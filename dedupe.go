@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Dedup scopes accepted by -dedup-scope.
+const (
+	dedupGlobal    = "global"
+	dedupPerDomain = "per-domain"
+	dedupNone      = "none"
+)
+
+func validateDedupScope(scope string) error {
+	switch scope {
+	case dedupGlobal, dedupPerDomain, dedupNone:
+		return nil
+	}
+	return fmt.Errorf("unknown dedup scope %q (expected %s, %s or %s)", scope, dedupGlobal, dedupPerDomain, dedupNone)
+}
+
+// dedupKey returns the key a result of domain is deduplicated under when
+// merging the results of several domains: the name itself, or with
+// -dedup-scope per-domain the name qualified by its input domain.
+func (s *SubHunter) dedupKey(domain, subdomain string) string {
+	if s.dedupScope == dedupPerDomain {
+		return domain + " " + subdomain
+	}
+	return subdomain
+}
+
+// dedupeStats records what dedupeSubdomains discarded.
+type dedupeStats struct {
+	duplicates int
+	invalid    int
+}
+
+// dedupeSubdomains normalizes and validates each entry using the same rules
+// as enumeration, returning the sorted unique subdomains. Entries may be
+// annotated or unicode SubHunter output.
+func (s *SubHunter) dedupeSubdomains(entries []string) ([]string, dedupeStats) {
+	var stats dedupeStats
+	seen := make(map[string]bool)
+
+	for _, entry := range entries {
+		subdomain := normalizeListEntry(entry)
+		if subdomain == "" {
+			continue // comment line
+		}
+		if !s.isValidSubdomain(subdomain) {
+			stats.invalid++
+			continue
+		}
+		if seen[subdomain] {
+			stats.duplicates++
+			continue
+		}
+		seen[subdomain] = true
+	}
+
+	result := make([]string, 0, len(seen))
+	for sub := range seen {
+		result = append(result, sub)
+	}
+	s.sortResults(result)
+
+	return result, stats
+}
+
+// dedupeFiles merges existing subdomain files without any network activity
+// and writes the result to outputs, or stdout if there are none.
+func (s *SubHunter) dedupeFiles(filenames []string, outputs []string) error {
+	var entries []string
+	for _, filename := range filenames {
+		lines, err := readLines(filename)
+		if err != nil {
+			return err
+		}
+		s.log("info", fmt.Sprintf("Loaded %d lines from", len(lines)), filename)
+		entries = append(entries, lines...)
+	}
+
+	subdomains, stats := s.dedupeSubdomains(entries)
+	s.log("success", fmt.Sprintf("Merged %d unique subdomains", len(subdomains)),
+		fmt.Sprintf("%d duplicates removed, %d invalid skipped", stats.duplicates, stats.invalid))
+
+	if len(outputs) > 0 {
+		return s.saveToFiles(subdomains, outputs)
+	}
+	if s.jsonOutput {
+		return s.writeResults(stdout, subdomains)
+	}
+	for _, sub := range subdomains {
+		s.printResult(sub)
+	}
+	return nil
+}
+
+// runDedupe implements the dedupe command.
+func runDedupe(args []string) {
+	fs := newFlagSet("dedupe", "dedupe [flags] file...")
+	silent := fs.Bool("silent", false, "silent mode (only results)")
+	format := registerFormatFlags(fs)
+	destination := registerDestinationFlags(fs)
+	files := parseInterspersed(fs, args)
+
+	printBanner(*silent)
+
+	if len(files) == 0 {
+		fmt.Printf("%s[ERR]%s dedupe requires at least one input file\n\n", pink, reset)
+		os.Exit(1)
+	}
+
+	hunter := NewSubHunter(defaultTimeout, 1, *silent)
+	exitOnError(format.apply(hunter))
+	outputs, err := destination.apply(hunter)
+	exitOnError(err)
+
+	if err := hunter.dedupeFiles(files, outputs); err != nil {
+		hunter.log("error", "Dedupe failed", err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDedupeSubdomains(t *testing.T) {
+	s := NewSubHunter(defaultTimeout, 1, true)
+	entries := []string{
+		"# SubHunter v1.0.1 — example.com — 2026-01-01T00:00:00Z — 4 subdomains",
+		"www.example.com # crt.sh/?id=5",
+		"WWW.example.com",
+		"bücher.example.com",
+		"*.BÜCHER.example.com",
+		"xn--bcher-kva.example.com",
+		"https://api.example.com:8443",
+		"3 dev.example.com",
+		"mail.example.com 192.0.2.1 AS64496 Example Org (2)",
+		"bad host.example.com",
+		"a|b.example.com",
+	}
+	want := []string{"api.example.com", "dev.example.com", "mail.example.com", "www.example.com", "xn--bcher-kva.example.com"}
+
+	got, stats := s.dedupeSubdomains(entries)
+	if !slices.Equal(got, want) {
+		t.Errorf("dedupeSubdomains = %q, want %q", got, want)
+	}
+	if stats.duplicates != 3 || stats.invalid != 2 {
+		t.Errorf("stats = %+v, want 3 duplicates and 2 invalid", stats)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// diffSubdomains returns the entries present only in newer (added) and only
+// in older (removed). Inputs need not be sorted.
+func diffSubdomains(older, newer []string) (added, removed []string) {
+	oldSet := make(mapSet, len(older))
+	for _, sub := range older {
+		oldSet.add(sub)
+	}
+	newSet := make(mapSet, len(newer))
+	for _, sub := range newer {
+		newSet.add(sub)
+	}
+
+	for _, sub := range newer {
+		if !oldSet[sub] {
+			added = append(added, sub)
+		}
+	}
+	for _, sub := range older {
+		if !newSet[sub] {
+			removed = append(removed, sub)
+		}
+	}
+	return added, removed
+}
+
+// diffFiles compares two result files and prints added/removed subdomains,
+// optionally writing each side to its own file.
+func (s *SubHunter) diffFiles(oldFile, newFile, addedFile, removedFile string) error {
+	load := func(filename string) ([]string, error) {
+		lines, err := readLines(filename)
+		if err != nil {
+			return nil, err
+		}
+		subdomains, _ := s.dedupeSubdomains(lines)
+		return subdomains, nil
+	}
+
+	older, err := load(oldFile)
+	if err != nil {
+		return err
+	}
+	newer, err := load(newFile)
+	if err != nil {
+		return err
+	}
+
+	added, removed := diffSubdomains(older, newer)
+
+	for _, sub := range added {
+		fmt.Fprintf(stdout, "+ %s\n", sub)
+	}
+	for _, sub := range removed {
+		fmt.Fprintf(stdout, "- %s\n", sub)
+	}
+	s.log("info", "Diff complete", fmt.Sprintf("%d added, %d removed", len(added), len(removed)))
+
+	if addedFile != "" {
+		if err := s.saveToFile(added, addedFile); err != nil {
+			return err
+		}
+	}
+	if removedFile != "" {
+		if err := s.saveToFile(removed, removedFile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runDiff implements the diff command.
+func runDiff(args []string) {
+	fs := newFlagSet("diff", "diff [flags] old.txt new.txt")
+	silent := fs.Bool("silent", false, "silent mode (only results)")
+	addedFile := fs.String("diff-output-added", "", "write added subdomains to this file")
+	removedFile := fs.String("diff-output-removed", "", "write removed subdomains to this file")
+	format := registerFormatFlags(fs)
+	files := parseInterspersed(fs, args)
+
+	printBanner(*silent)
+
+	if len(files) != 2 {
+		fmt.Printf("%s[ERR]%s diff requires exactly two files: old new\n\n", pink, reset)
+		os.Exit(1)
+	}
+
+	hunter := NewSubHunter(defaultTimeout, 1, *silent)
+	exitOnError(format.apply(hunter))
+
+	if err := hunter.diffFiles(files[0], files[1], *addedFile, *removedFile); err != nil {
+		hunter.log("error", "Diff failed", err.Error())
+		os.Exit(1)
+	}
+}
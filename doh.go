@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dohQuery sends a single RFC 8484 wire-format query to the -doh resolver
+// and returns the answer records. A name that does not exist yields no
+// records rather than an error, so only resolver failures are errors.
+func (s *SubHunter) dohQuery(ctx context.Context, name string, qtype dnsmessage.Type) ([]dnsmessage.Resource, error) {
+	fqdn, err := dnsmessage.NewName(strings.TrimSuffix(name, ".") + ".")
+	if err != nil {
+		return nil, err
+	}
+	query := dnsmessage.Message{
+		// ID 0 keeps GET requests cacheable, as RFC 8484 recommends
+		Header:    dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: fqdn, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	sep := "?"
+	if strings.Contains(s.dohURL, "?") {
+		sep = "&"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.dohURL+sep+"dns="+base64.RawURLEncoding.EncodeToString(packed), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, body, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH %w", ErrHTTPStatus{resp.StatusCode})
+	}
+
+	var reply dnsmessage.Message
+	if err := reply.Unpack(body); err != nil {
+		return nil, fmt.Errorf("DoH reply: %v", err)
+	}
+	switch reply.RCode {
+	case dnsmessage.RCodeSuccess:
+		return reply.Answers, nil
+	case dnsmessage.RCodeNameError:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("DoH %s for %s", reply.RCode, name)
+}
+
+// dohLookupIPs resolves the A and AAAA records of host over DoH. Answers
+// to CNAME chains carry the final addresses, which are all collected.
+func (s *SubHunter) dohLookupIPs(ctx context.Context, host string) ([]net.IP, error) {
+	var ips []net.IP
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		answers, err := s.dohQuery(ctx, host, qtype)
+		if err != nil {
+			return nil, err
+		}
+		for _, answer := range answers {
+			switch body := answer.Body.(type) {
+			case *dnsmessage.AResource:
+				ips = append(ips, net.IP(body.A[:]))
+			case *dnsmessage.AAAAResource:
+				ips = append(ips, net.IP(body.AAAA[:]))
+			}
+		}
+	}
+	return ips, nil
+}
+
+// dohLookupNS returns the name servers of a domain over DoH, without
+// trailing dots.
+func (s *SubHunter) dohLookupNS(ctx context.Context, domain string) ([]string, error) {
+	answers, err := s.dohQuery(ctx, domain, dnsmessage.TypeNS)
+	if err != nil {
+		return nil, err
+	}
+	var hosts []string
+	for _, answer := range answers {
+		if body, ok := answer.Body.(*dnsmessage.NSResource); ok {
+			hosts = append(hosts, strings.TrimSuffix(strings.ToLower(body.NS.String()), "."))
+		}
+	}
+	return hosts, nil
+}
+
+// dohFailed logs, once per run, that DoH failed and lookups fall back to
+// the system resolver.
+func (s *SubHunter) dohFailed(err error) {
+	if s.dohWarned.CompareAndSwap(false, true) {
+		s.log("warn", "DoH lookup failed, falling back to the system resolver", err.Error())
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestReadLinesEncoding(t *testing.T) {
+	want := []string{"example.com", "api.example.org", "dev.example.net"}
+	tests := []struct {
+		file     string
+		encoding string
+	}{
+		{"domains-utf8.txt", ""},
+		{"domains-utf8-bom.txt", ""},
+		{"domains-utf16le.txt", "UTF-16LE"},
+		{"domains-utf16be.txt", "UTF-16BE"},
+	}
+	for _, tt := range tests {
+		lines, encoding, err := readLinesEncoding(filepath.Join("testdata", tt.file))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.file, err)
+		}
+		if encoding != tt.encoding {
+			t.Errorf("%s: encoding = %q, want %q", tt.file, encoding, tt.encoding)
+		}
+		if !slices.Equal(lines, want) {
+			t.Errorf("%s: lines = %q, want %q", tt.file, lines, want)
+		}
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		data     []byte
+		want     string
+		encoding string
+	}{
+		{[]byte("a.com"), "a.com", ""},
+		{[]byte("\xef\xbb\xbfa.com"), "a.com", ""},
+		{[]byte("\xff\xfea\x00.\x00c\x00o\x00m\x00"), "a.com", "UTF-16LE"},
+		{[]byte("\xfe\xff\x00a\x00.\x00c\x00o\x00m"), "a.com", "UTF-16BE"},
+		{[]byte("\xff\xfe\xfc\x00.\x00d\x00e\x00"), "ü.de", "UTF-16LE"},
+		{nil, "", ""},
+	}
+	for _, tt := range tests {
+		got, encoding := decodeText(tt.data)
+		if string(got) != tt.want || encoding != tt.encoding {
+			t.Errorf("decodeText(%q) = %q, %q; want %q, %q", tt.data, got, encoding, tt.want, tt.encoding)
+		}
+	}
+}
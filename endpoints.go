@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// defaultEndpoint is the crt.sh front-end queried unless -crtsh-url names
+// others.
+const defaultEndpoint = "https://crt.sh"
+
+// parseEndpoints reads a comma-separated -crtsh-url list of crt.sh base
+// URLs, dropping trailing slashes.
+func parseEndpoints(list string) ([]string, error) {
+	var endpoints []string
+	for _, endpoint := range strings.Split(list, ",") {
+		endpoint = strings.TrimRight(strings.TrimSpace(endpoint), "/")
+		if endpoint == "" {
+			continue
+		}
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("invalid -crtsh-url %q (expected an http(s) base URL)", endpoint)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("-crtsh-url lists no endpoints")
+	}
+	return endpoints, nil
+}
+
+// endpointOrder returns the endpoints in the order to try them: starting
+// with the one that last answered, then the others in -crtsh-url order.
+func (s *SubHunter) endpointOrder() []string {
+	s.mu.Lock()
+	preferred := s.preferredEndpoint
+	s.mu.Unlock()
+
+	order := make([]string, 0, len(s.endpoints))
+	for i := range s.endpoints {
+		order = append(order, s.endpoints[(preferred+i)%len(s.endpoints)])
+	}
+	return order
+}
+
+// endpointAnswered records that endpoint served a query, making it the
+// first one tried next.
+func (s *SubHunter) endpointAnswered(endpoint string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.endpointHits == nil {
+		s.endpointHits = make(map[string]int)
+	}
+	s.endpointHits[endpoint]++
+	for i, e := range s.endpoints {
+		if e == endpoint {
+			s.preferredEndpoint = i
+		}
+	}
+}
+
+// logEndpointHits reports how many queries each endpoint answered, when
+// there was more than one to choose from.
+func (s *SubHunter) logEndpointHits() {
+	if len(s.endpoints) < 2 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, endpoint := range s.endpoints {
+		s.log("info", fmt.Sprintf("Endpoint answered %d queries:", s.endpointHits[endpoint]), endpoint)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The errors returned by queryAPI and fetchCertificates. Callers tell a
+// transient crt.sh outage from a permanent failure with errors.Is and
+// errors.As; the CLI prints their messages as they are.
+
+// ErrHTMLResponse is returned when crt.sh answers a JSON query with an HTML
+// page, which it does when overloaded.
+var ErrHTMLResponse = errors.New("API returned HTML instead of JSON")
+
+// ErrInterrupted is returned when the run is interrupted while waiting to
+// retry.
+var ErrInterrupted = errors.New("interrupted")
+
+// ErrEmptyResponse is the retryable error for a valid but empty result
+// with -retry-on-empty. It is not returned once the retries run out: the
+// result is then taken as empty.
+var ErrEmptyResponse = errors.New("API returned no certificates")
+
+// ErrHTTPStatus is returned for a response with an unexpected status code.
+type ErrHTTPStatus struct {
+	Code int
+}
+
+func (e ErrHTTPStatus) Error() string {
+	return fmt.Sprintf("HTTP %d", e.Code)
+}
+
+// ErrDecode is returned when a response body is not a valid crt.sh JSON
+// array, including when it was cut off.
+type ErrDecode struct {
+	Err error
+}
+
+func (e ErrDecode) Error() string {
+	return fmt.Sprintf("JSON decode failed: %v", e.Err)
+}
+
+func (e ErrDecode) Unwrap() error {
+	return e.Err
+}
+
+// ErrMaxRetries is returned when every attempt of a query failed. Last is
+// the error of the final attempt, so errors.As also finds the kind of
+// failure that was retried.
+type ErrMaxRetries struct {
+	Last error
+}
+
+func (e ErrMaxRetries) Error() string {
+	return fmt.Sprintf("max retries exceeded: %v", e.Last)
+}
+
+func (e ErrMaxRetries) Unwrap() error {
+	return e.Last
+}
+
+// ErrResponseTooLarge is returned when a response body exceeds
+// -max-response-size. Retrying would only download it again, so the query
+// fails at once.
+type ErrResponseTooLarge struct {
+	Limit int64
+}
+
+func (e ErrResponseTooLarge) Error() string {
+	return fmt.Sprintf("response larger than the %d MB limit", e.Limit>>20)
+}
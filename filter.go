@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// defaultInternalSuffixes are suffixes commonly used for hosts that are not
+// reachable from the internet.
+var defaultInternalSuffixes = []string{
+	"local", "localhost", "localdomain", "internal", "intranet", "corp",
+	"lan", "home", "home.arpa", "private", "test", "invalid",
+}
+
+// filterResults applies the post-extraction filters to a result set,
+// preserving its order.
+func (s *SubHunter) filterResults(subdomains []string) []string {
+	if !s.skipInternal && s.subtract == nil {
+		return subdomains
+	}
+
+	kept := subdomains[:0]
+	for _, sub := range subdomains {
+		if s.skipInternal && s.isInternal(sub) {
+			continue
+		}
+		if s.subtract[sub] {
+			s.mu.Lock()
+			s.subtracted.add(sub)
+			s.mu.Unlock()
+			continue
+		}
+		kept = append(kept, sub)
+	}
+	return kept
+}
+
+// loadSubtract reads the -subtract list of subdomains already known from
+// other sources. Entries are normalized like results, and the trailing dot
+// of passive DNS exports is dropped; invalid ones are skipped with a
+// warning.
+func (s *SubHunter) loadSubtract(filename string) (map[string]bool, error) {
+	lines, err := readLines(filename)
+	if err != nil {
+		return nil, err
+	}
+	subtract := make(map[string]bool, len(lines))
+	invalid := 0
+	for _, line := range lines {
+		sub := strings.TrimSuffix(normalizeSubdomain(line), ".")
+		if !s.isValidSubdomain(sub) {
+			invalid++
+			continue
+		}
+		subtract[sub] = true
+	}
+	if invalid > 0 {
+		s.log("warn", fmt.Sprintf("Skipped %d invalid entries in", invalid), filename)
+	}
+	s.log("info", fmt.Sprintf("Loaded %d subdomains to subtract from", len(subtract)), filename)
+	return subtract, nil
+}
+
+// isInternal reports whether name ends in an internal suffix or in a TLD
+// that is not on the public suffix list.
+func (s *SubHunter) isInternal(name string) bool {
+	for _, suffix := range s.internalSuffixes {
+		if name == suffix || strings.HasSuffix(name, "."+suffix) {
+			return true
+		}
+	}
+
+	suffix, icann := publicsuffix.PublicSuffix(name)
+	// Unlisted TLDs fall back to the implicit "*" rule: a single,
+	// non-ICANN label.
+	return !icann && !strings.Contains(suffix, ".")
+}
+
+// parseSuffixList splits a comma-separated suffix list, dropping empty
+// entries and leading dots.
+func parseSuffixList(value string) []string {
+	var suffixes []string
+	for _, suffix := range strings.Split(value, ",") {
+		suffix = strings.Trim(strings.ToLower(strings.TrimSpace(suffix)), ".")
+		if suffix != "" {
+			suffixes = append(suffixes, suffix)
+		}
+	}
+	return suffixes
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// flagRules describe how the flags of a command may be combined and which
+// values they accept. They are checked once after parsing so that every
+// problem is reported the same way, before any work starts.
+type flagRules struct {
+	repeatable []string            // flags that may be given more than once
+	conflicts  [][]string          // at most one flag of each group may be set; "name=value" matches one value only
+	requires   map[string][]string // flag -> flags that must be set with it
+	minimums   map[string]float64  // numeric or duration flag -> lowest accepted value
+}
+
+// enumRules covers the enum command.
+var enumRules = flagRules{
+	repeatable: []string{"l", "o"},
+	conflicts: [][]string{
+		{"d", "l", "sha256", "match-pattern", "stdin-json"},
+		{"stdin-json", "o"},
+		{"stdin-json", "output-url"},
+		{"stdin-json", "low-memory"},
+		{"stdin-json", "resolve"},
+		{"stdin-json", "permute"},
+		{"stdin-json", "webhook"},
+		{"raw", "sha256", "match-pattern", "stdin-json"},
+		{"raw", "json"},
+		{"raw", "hosts-format"},
+		{"raw", "merge"},
+		{"raw", "low-memory"},
+		{"raw", "resolve"},
+		{"raw", "permute"},
+		{"raw", "labels-only"},
+		{"raw", "include-san-domains"},
+		{"raw", "timeline"},
+		{"quiet-summary", "json"},
+		{"quiet-summary", "hosts-format"},
+		{"quiet-summary", "ns-candidates"},
+		{"quiet-summary", "include-san-domains"},
+		{"quiet-summary", "stdin-json"},
+		{"quiet-summary", "raw"},
+		{"ns-candidates", "json"},
+		{"ns-candidates", "hosts-format"},
+		{"ns-candidates", "merge"},
+		{"ns-candidates", "scan-ports"},
+		{"ns-candidates", "labels-only"},
+		{"hosts-format", "json"},
+		{"hosts-format", "merge"},
+		{"hosts-format", "scan-ports"},
+		{"scan-ports", "urls"},
+		{"scan-ports", "both-schemes"},
+		{"scan-ports", "ports"},
+		{"scope", "sha256"},
+		{"scope", "match-pattern"},
+		{"include-san-domains", "json"},
+		{"no-dedupe", "resolve"},
+		{"no-dedupe", "permute"},
+		{"no-dedupe", "merge"},
+		{"unsorted", "sort"},
+		{"unsorted", "head"},
+		{"unsorted", "with-id"},
+		{"unsorted", "with-counts"},
+		{"labels-only", "stdin-json"},
+		{"labels-only", "low-memory"},
+		{"labels-only", "merge"},
+		{"labels-only", "urls"},
+		{"labels-only", "hosts-format"},
+		{"labels-only", "scan-ports"},
+		{"labels-only", "with-id"},
+		{"labels-only", "with-counts"},
+		{"labels-only", "with-asn"},
+		{"low-memory", "resolve"},
+		{"low-memory", "permute"},
+		{"low-memory", "merge"},
+		{"low-memory", "webhook"},
+		{"low-memory", "wildcard-tree"},
+		{"low-memory", "group-by-cname"},
+		{"raw", "group-by-cname"},
+		{"raw", "ca-stats"},
+		{"raw", "subtract"},
+		{"raw", "header-comment"},
+		{"markdown", "json"},
+		{"markdown", "hosts-format"},
+		{"markdown", "ns-candidates"},
+		{"markdown", "raw"},
+		{"markdown", "low-memory"},
+		{"markdown", "labels-only"},
+		{"markdown", "watch"},
+		{"markdown", "stdin-json"},
+		{"cn-only", "sha256", "match-pattern"},
+		{"cn-only", "fallback-text"},
+		{"cn-only", "include-san-domains"},
+		{"freq", "json"},
+		{"freq", "hosts-format"},
+		{"freq", "ns-candidates"},
+		{"freq", "markdown"},
+		{"freq", "with-counts"},
+		{"freq", "raw"},
+		{"freq", "low-memory"},
+		{"freq", "labels-only"},
+		{"freq", "no-dedupe"},
+		{"freq", "watch"},
+		{"freq", "stdin-json"},
+		{"label-stats-csv", "low-memory"},
+		{"label-stats-csv", "raw"},
+		{"label-stats-csv", "watch"},
+		{"label-stats-csv", "stdin-json"},
+		{"watch", "o"},
+		{"watch", "output-url"},
+		{"watch", "json"},
+		{"watch", "raw"},
+		{"watch", "resolve"},
+		{"watch", "permute"},
+		{"watch", "tld-expand"},
+		{"watch", "webhook"},
+		{"low-memory", "header-comment"},
+		// -dedup-scope none is -no-dedupe and is limited the same way;
+		// with per-domain, -merge would drop the repeats again
+		{"dedup-scope=per-domain", "merge"},
+		{"dedup-scope=none", "merge"},
+		{"dedup-scope=none", "resolve"},
+		{"dedup-scope=none", "permute"},
+	},
+	requires: map[string][]string{
+		"ip-range":            {"resolve"},
+		"ip-range-exclude":    {"resolve"},
+		"hosts-format":        {"resolve"},
+		"ns-candidates":       {"resolve"},
+		"watch":               {"d"},
+		"scan-ports":          {"resolve"},
+		"with-asn":            {"resolve", "asn-db"},
+		"known":               {"webhook"},
+		"manifest":            {"o"},
+		"tld-expand":          {"d"},
+		"ordered-output":      {"concurrent"},
+		"log-append":          {"log-file"},
+		"include-san-domains": {"d"},
+	},
+	minimums: map[string]float64{
+		"c":                        1, // sizes the worker semaphores
+		"t":                        0,
+		"retry-budget":             0,
+		"timeout-retry-multiplier": 0,
+		"backoff-max":              0,
+		"max-response-size":        0,
+		"mem-cache-size":           0,
+		"per-domain-limit":         0,
+		"batch-size":               0,
+		"watch":                    0,
+	},
+}
+
+// countedValue counts how often a flag is set on the command line.
+type countedValue struct {
+	flag.Value
+	count *int
+}
+
+func (v *countedValue) Set(value string) error {
+	*v.count++
+	return v.Value.Set(value)
+}
+
+func (v *countedValue) IsBoolFlag() bool {
+	b, ok := v.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// countFlags makes fs record how often each flag is given while parsing.
+// The original values are put back before usage is printed, which inspects
+// their types.
+func countFlags(fs *flag.FlagSet) map[string]*int {
+	counts := make(map[string]*int)
+	originals := make(map[string]flag.Value)
+	fs.VisitAll(func(f *flag.Flag) {
+		counts[f.Name] = new(int)
+		originals[f.Name] = f.Value
+		f.Value = &countedValue{Value: f.Value, count: counts[f.Name]}
+	})
+
+	usage := fs.Usage
+	fs.Usage = func() {
+		fs.VisitAll(func(f *flag.Flag) {
+			f.Value = originals[f.Name]
+		})
+		usage()
+	}
+	return counts
+}
+
+// check validates the parsed flags of fs against the rules. A flag given
+// an empty value, or a boolean explicitly set to false, does not count as
+// set.
+func (r flagRules) check(fs *flag.FlagSet, counts map[string]*int) error {
+	set := func(name string) bool {
+		name, want, valued := strings.Cut(name, "=")
+		f := fs.Lookup(name)
+		if f == nil || *counts[name] == 0 {
+			return false
+		}
+		value := f.Value.String()
+		if valued {
+			return value == want
+		}
+		return value != "" && value != "false"
+	}
+
+	repeatable := make(map[string]bool)
+	for _, name := range r.repeatable {
+		repeatable[name] = true
+	}
+	var problems []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if n := *counts[f.Name]; n > 1 && !repeatable[f.Name] {
+			problems = append(problems, fmt.Sprintf("-%s is given %d times", f.Name, n))
+		}
+	})
+
+	for _, group := range r.conflicts {
+		var given []string
+		for _, name := range group {
+			if set(name) {
+				given = append(given, "-"+name)
+			}
+		}
+		if len(given) > 1 {
+			problems = append(problems, fmt.Sprintf("cannot combine %s", strings.Join(given, " and ")))
+		}
+	}
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if !set(f.Name) {
+			return
+		}
+		var missing []string
+		for _, name := range r.requires[f.Name] {
+			if !set(name) {
+				missing = append(missing, "-"+name)
+			}
+		}
+		if len(missing) > 0 {
+			problems = append(problems, fmt.Sprintf("-%s requires %s", f.Name, strings.Join(missing, " and ")))
+		}
+	})
+
+	fs.VisitAll(func(f *flag.Flag) {
+		minimum, ok := r.minimums[f.Name]
+		if !ok {
+			return
+		}
+		value, ok := numericValue(f.Value.String())
+		switch {
+		case !ok || value >= minimum:
+		case minimum == 0:
+			problems = append(problems, fmt.Sprintf("-%s cannot be negative", f.Name))
+		default:
+			problems = append(problems, fmt.Sprintf("-%s must be at least %v", f.Name, minimum))
+		}
+	})
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// numericValue parses the value of a numeric or duration flag, durations
+// in nanoseconds.
+func numericValue(value string) (float64, bool) {
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		return n, true
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return float64(d), true
+	}
+	return 0, false
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestFlagRulesMinimums(t *testing.T) {
+	rules := flagRules{minimums: map[string]float64{"c": 1, "t": 0, "wait": 0}}
+	tests := []struct {
+		args []string
+		ok   bool
+	}{
+		{nil, true},
+		{[]string{"-c", "1", "-t", "0", "-wait", "0s"}, true},
+		{[]string{"-c", "0"}, false},
+		{[]string{"-c", "-3"}, false},
+		{[]string{"-t", "-1"}, false},
+		{[]string{"-wait", "-5s"}, false},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		fs.Int("c", 5, "")
+		fs.Int("t", 60, "")
+		fs.Duration("wait", time.Second, "")
+		counts := countFlags(fs)
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		if err := rules.check(fs, counts); (err == nil) != tt.ok {
+			t.Errorf("check(%q) = %v, want ok %v", tt.args, err, tt.ok)
+		}
+	}
+}
+
+func TestFlagRulesValueConflicts(t *testing.T) {
+	rules := flagRules{conflicts: [][]string{{"scope=none", "merge"}}}
+	tests := []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"-merge"}, true},
+		{[]string{"-scope", "global", "-merge"}, true},
+		{[]string{"-scope", "none"}, true},
+		{[]string{"-scope", "none", "-merge"}, false},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		fs.String("scope", "global", "")
+		fs.Bool("merge", false, "")
+		counts := countFlags(fs)
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		if err := rules.check(fs, counts); (err == nil) != tt.ok {
+			t.Errorf("check(%q) = %v, want ok %v", tt.args, err, tt.ok)
+		}
+	}
+}
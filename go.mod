@@ -1,3 +1,7 @@
 module github.com/aptspider/SubHunter/v2
 
 go 1.21
+
+require golang.org/x/net v0.24.0
+
+require golang.org/x/text v0.14.0 // indirect
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// parseListEntry reads one line of a -l list. Besides plain domains, a list
+// may hold NDJSON objects like {"domain": "example.com", "owner": "web"};
+// their other fields are metadata carried into the JSON results of that
+// domain. Plain lines have no metadata.
+func parseListEntry(line string) (string, map[string]json.RawMessage, error) {
+	if !strings.HasPrefix(line, "{") {
+		return line, nil, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return "", nil, fmt.Errorf("invalid JSON list entry: %v", err)
+	}
+	var domain string
+	if err := json.Unmarshal(fields["domain"], &domain); err != nil || strings.TrimSpace(domain) == "" {
+		return "", nil, fmt.Errorf("JSON list entry without a \"domain\" string: %s", line)
+	}
+	delete(fields, "domain")
+	if len(fields) == 0 {
+		fields = nil
+	}
+	return strings.TrimSpace(domain), fields, nil
+}
+
+// setInputMeta stores the metadata of an input domain.
+func (s *SubHunter) setInputMeta(domain string, meta map[string]json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inputMeta == nil {
+		s.inputMeta = make(map[string]map[string]json.RawMessage)
+	}
+	s.inputMeta[strings.ToLower(domain)] = meta
+}
+
+// metaFor returns the metadata of the input domain a subdomain was found
+// under: the closest input domain that is the name itself or a parent.
+func (s *SubHunter) metaFor(subdomain string) map[string]json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inputMeta == nil {
+		return nil
+	}
+	for name := subdomain; ; {
+		if meta, ok := s.inputMeta[name]; ok {
+			return meta
+		}
+		i := strings.Index(name, ".")
+		if i < 0 {
+			return nil
+		}
+		name = name[i+1:]
+	}
+}
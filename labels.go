@@ -0,0 +1,32 @@
+package main
+
+// relativeLabels strips from each subdomain the queried domain it was found
+// under, for -labels-only: "dev.api.example.com" becomes "dev.api". Names
+// outside every queried domain lose their registrable domain instead. A
+// result equal to its apex has no labels left and is dropped, and labels
+// shared by several apexes are listed once.
+func (s *SubHunter) relativeLabels(subdomains []string) []string {
+	s.mu.Lock()
+	queried := make(map[string]bool, len(s.domainCounts))
+	for domain := range s.domainCounts {
+		queried[domain] = true
+	}
+	s.mu.Unlock()
+
+	seen := make(mapSet)
+	var labels []string
+	for _, sub := range subdomains {
+		apex := coveringDomain(sub, queried)
+		if apex == "" {
+			apex = registrableDomain(sub)
+		}
+		if len(sub) <= len(apex) {
+			continue
+		}
+		if label := sub[:len(sub)-len(apex)-1]; seen.add(label) {
+			labels = append(labels, label)
+		}
+	}
+	s.sortResults(labels)
+	return labels
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/csv"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LabelCount is how many results have a given leftmost label.
+type LabelCount struct {
+	Label string
+	Count int
+}
+
+// labelCounts tallies the leftmost label of every result below its
+// registrable domain, most common first and alphabetically among equal
+// counts. A registrable domain itself has no such label and is skipped.
+func labelCounts(subdomains []string) []LabelCount {
+	counts := make(map[string]int)
+	for _, sub := range subdomains {
+		if len(sub) <= len(registrableDomain(sub)) {
+			continue
+		}
+		label, _, _ := strings.Cut(strings.TrimPrefix(sub, "*."), ".")
+		counts[label]++
+	}
+
+	tally := make([]LabelCount, 0, len(counts))
+	for label, count := range counts {
+		tally = append(tally, LabelCount{Label: label, Count: count})
+	}
+	sort.Slice(tally, func(i, j int) bool {
+		if tally[i].Count != tally[j].Count {
+			return tally[i].Count > tally[j].Count
+		}
+		return tally[i].Label < tally[j].Label
+	})
+	return tally
+}
+
+// writeLabelStats writes every distinct leftmost label with its count to
+// the -label-stats-csv file.
+func (s *SubHunter) writeLabelStats(subdomains []string, filename string) error {
+	out, err := s.openOutput(filename)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(out)
+	writer.UseCRLF = s.lineEnding == lineEndingCRLF
+	writer.Write([]string{"label", "count"})
+	for _, lc := range labelCounts(subdomains) {
+		writer.Write([]string{lc.Label, strconv.Itoa(lc.Count)})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	s.log("success", "Saved label statistics to", filename)
+	return nil
+}
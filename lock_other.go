@@ -0,0 +1,25 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// tryLockFile attempts to take an exclusive lock on path without blocking.
+// Without flock, the lock is the existence of the file itself.
+func tryLockFile(path string) (unlock func(), ok bool, err error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	return func() {
+		file.Close()
+		os.Remove(path)
+	}, true, nil
+}
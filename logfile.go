@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// logLabels are the plain-text level tags used in -log-file.
+var logLabels = map[string]string{
+	"info":    "INF",
+	"success": "SUC",
+	"error":   "ERR",
+	"warn":    "WAR",
+	"found":   "FND",
+	"run":     "RUN",
+	"retry":   "RTY",
+}
+
+// openLogFile opens the -log-file. Without appending, an existing log is
+// first rotated to <path>.1 so the previous run's log is kept.
+func openLogFile(path string, appendLog bool) (*os.File, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !appendLog {
+		if _, err := os.Stat(path); err == nil {
+			if err := os.Rename(path, path+".1"); err != nil {
+				return nil, fmt.Errorf("rotating log file: %v", err)
+			}
+		}
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	}
+	return os.OpenFile(path, flags, 0644)
+}
+
+// writeLogFile writes a log entry to the -log-file, without colors and with
+// a full timestamp. It is written in silent mode too.
+func (s *SubHunter) writeLogFile(level, message, data string) {
+	if s.logFile == nil {
+		return
+	}
+	label := logLabels[level]
+	if label == "" {
+		label = strings.ToUpper(level)
+	}
+	line := fmt.Sprintf("%s [%s] %s", time.Now().Format(time.RFC3339), label, message)
+	if data != "" {
+		line += " " + data
+	}
+	fmt.Fprintln(s.logFile, line)
+}
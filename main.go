@@ -2,17 +2,25 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
-	"flag"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"golang.org/x/net/idna"
 )
 
 const (
@@ -30,6 +38,12 @@ const (
 `
 )
 
+// Output encodings accepted by -output-encoding.
+const (
+	encodingASCII   = "ascii"
+	encodingUnicode = "unicode"
+)
+
 var (
 	pink    = "\033[95m"
 	magenta = "\033[35m"
@@ -38,26 +52,148 @@ var (
 	reset   = "\033[0m"
 )
 
+const userAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"
+
 type CRTResponse struct {
-	NameValue string `json:"name_value"`
+	ID         int64  `json:"id"`
+	NameValue  string `json:"name_value"`
+	CommonName string `json:"common_name"`
+	NotBefore  string `json:"not_before"`
+	IssuerName string `json:"issuer_name"`
 }
 
 type SubHunter struct {
-	timeout     time.Duration
-	concurrency int
-	silent      bool
-	client      *http.Client
-	totalFound  int
-	mu          sync.Mutex
-	maxRetries  int
+	timeout            time.Duration
+	concurrency        int
+	silent             bool
+	quietSummary       bool // -quiet-summary: stdout gets only the final count
+	client             *http.Client
+	totalFound         int
+	mu                 sync.Mutex
+	maxRetries         int
+	urls               bool
+	bothSchemes        bool
+	ports              []int
+	lowMemory          bool
+	bloomSize          int
+	stream             io.Writer
+	withID             bool
+	certIDs            map[string]int64
+	sortMode           string
+	outputEncoding     string
+	retryAfterMax      time.Duration
+	retryMultiplier    float64
+	retryOnEmpty       bool           // -retry-on-empty: an empty result is retried
+	endpoints          []string       // -crtsh-url front-ends, tried in failover order
+	preferredEndpoint  int            // index of the endpoint that last answered
+	endpointHits       map[string]int // queries answered per endpoint
+	cnOnly             bool           // -cn-only: search and match certificate common names only
+	tryAltQuery        bool           // -try-alt-query: query the bare domain after an empty result
+	backoffMax         time.Duration  // ceiling on the computed retry backoff; 0 for none
+	skipInternal       bool
+	internalSuffixes   []string
+	jsonOutput         bool
+	pretty             bool
+	merge              bool
+	lockTimeout        time.Duration
+	timeline           bool
+	timelineCounts     map[string]int
+	timelineSeen       map[int64]bool
+	caStats            bool
+	headerTarget       string          // target named in the -header-comment line; "" for no header
+	subtract           map[string]bool // -subtract: subdomains dropped from the results
+	subtracted         mapSet          // distinct results dropped by -subtract
+	flagSuspicious     bool
+	fields             map[string]bool // -fields selection for JSON results; nil keeps all
+	caCounts           map[string]int  // matching certificates per CA, for -ca-stats
+	caSeen             map[int64]bool  // certificate IDs already counted in caCounts
+	netSlots           chan struct{}   // bounds simultaneous network requests; nil means unlimited
+	resolve            bool
+	ips                map[string][]net.IP
+	ipRanges           []*net.IPNet
+	ipExcludes         []*net.IPNet
+	syslog             syslogger
+	trackFirstSeen     bool
+	firstSeen          map[string]time.Time
+	permuteWords       []string
+	fallbackText       bool
+	withASN            bool
+	asnDB              *asnDB // nil when no dataset could be loaded
+	head               bool
+	headShown          bool
+	retryBudget        *atomic.Int64 // retries left for the whole run; nil means unlimited
+	retryBudgetSpent   atomic.Bool
+	hostsFormat        bool
+	markdown           bool
+	freq               bool
+	ramp               time.Duration // window over which concurrent workers start
+	basicAuthUser      string
+	basicAuthPass      string
+	scanPorts          []int
+	scanTimeout        time.Duration
+	openPorts          map[string][]int
+	onlyResolvableApex bool
+	perDomainLimit     int // -per-domain-limit; 0 for no cap
+	batchSize          int
+	batchPause         time.Duration
+	ctx                context.Context // canceled when the run is interrupted
+	withCounts         bool
+	countCerts         bool // track certCounts, for -with-counts or -sort count
+	certCounts         map[string]int
+	lineEnding         string
+	printMu            sync.Mutex
+	printedLine        bool // a result line has been printed, for -line-ending none
+	dohURL             string
+	dohWarned          atomic.Bool
+	memCache           *resultCache    // nil when -mem-cache-size is 0
+	scope              map[string]bool // -scope allowlist; nil allows everything
+	sources            map[string]bool // crt.sh endpoints queried, for -manifest
+	domainCounts       map[string]int
+	includeSANDomains  bool
+	sanDomains         []string // other-apex hostnames from matching certificates
+	apiToken           string   // sent as a bearer token; never logged
+	noDedupe           bool     // keep every occurrence, unsorted
+	dedupScope         string   // how results of several domains are merged
+	patternMu          sync.Mutex
+	patterns           map[string]*regexp.Regexp // compiled per-domain patterns
+	unsorted           bool
+	streamLive         bool   // -unsorted single-domain run: print as certificates arrive
+	liveSeen           mapSet // subdomains already printed live
+	wildcardTree       bool
+	wildcards          map[string]bool // bases of wildcard names, for -wildcard-tree
+	cooldown           time.Duration   // global pause after a 429; 0 disables it
+	cooldownMu         sync.Mutex
+	cooldownUntil      time.Time
+	maxResponseSize    int64 // bytes; 0 means unlimited
+	orderedOutput      bool
+	inputMeta          map[string]map[string]json.RawMessage // NDJSON -l fields by domain
+	logFile            io.Writer                             // -log-file; nil when not logging to a file
+	rawOut             io.Writer                             // -raw destination; nil unless saving raw responses
+	rawLines           bool                                  // -raw writes one NDJSON line per domain
+	nsCandidates       bool
+	nameservers        map[string][]string // apex -> name servers, for -ns-candidates
 }
 
 func NewSubHunter(timeout int, concurrency int, silent bool) *SubHunter {
 	return &SubHunter{
-		timeout:     time.Duration(timeout) * time.Second,
-		concurrency: concurrency,
-		silent:      silent,
-		maxRetries:  3, // Try 3 times before giving up
+		timeout:         time.Duration(timeout) * time.Second,
+		concurrency:     concurrency,
+		silent:          silent,
+		maxRetries:      3, // Try 3 times before giving up
+		retryMultiplier: 1,
+		retryAfterMax:   time.Minute,
+		backoffMax:      defaultBackoffMax,
+		certIDs:         make(map[string]int64),
+		timelineCounts:  make(map[string]int),
+		timelineSeen:    make(map[int64]bool),
+		ips:             make(map[string][]net.IP),
+		firstSeen:       make(map[string]time.Time),
+		openPorts:       make(map[string][]int),
+		certCounts:      make(map[string]int),
+		domainCounts:    make(map[string]int),
+		scanTimeout:     defaultScanTimeout,
+		ctx:             context.Background(),
+		endpoints:       []string{defaultEndpoint},
 		client: &http.Client{
 			Timeout: time.Duration(timeout) * time.Second,
 		},
@@ -65,6 +201,7 @@ func NewSubHunter(timeout int, concurrency int, silent bool) *SubHunter {
 }
 
 func (s *SubHunter) log(level, message, data string) {
+	s.writeLogFile(level, message, data)
 	if s.silent {
 		return
 	}
@@ -90,336 +227,1984 @@ func (s *SubHunter) log(level, message, data string) {
 	}
 
 	if data != "" {
-		fmt.Printf("%s%s%s %s %s %s%s%s%s\n", dim, timestamp, reset, icon, message, pink, bold, data, reset)
+		fmt.Fprintf(stdout, "%s%s%s %s %s %s%s%s%s\n", dim, timestamp, reset, icon, message, pink, bold, data, reset)
 	} else {
-		fmt.Printf("%s%s%s %s %s\n", dim, timestamp, reset, icon, message)
+		fmt.Fprintf(stdout, "%s%s%s %s %s\n", dim, timestamp, reset, icon, message)
 	}
 }
 
 func (s *SubHunter) printResult(subdomain string) {
-	if !s.silent {
-		fmt.Printf("%s[R]%s %s\n", pink, reset, subdomain)
+	if s.quietSummary {
+		return // only the final count goes to stdout
+	}
+	for _, line := range s.formatResult(subdomain) {
+		if !s.silent {
+			line = fmt.Sprintf("%s[R]%s %s", pink, reset, line)
+		}
+		s.printLine(line)
+	}
+}
+
+// printLine writes a result line to stdout with the configured terminator.
+// With -line-ending none the separator goes before every line but the
+// first, so the output never ends in a newline.
+func (s *SubHunter) printLine(line string) {
+	if s.lineEnding != lineEndingNone {
+		fmt.Fprint(stdout, line+s.eol())
+		return
+	}
+
+	s.printMu.Lock()
+	defer s.printMu.Unlock()
+	if s.printedLine {
+		line = "\n" + line
+	}
+	s.printedLine = true
+	fmt.Fprint(stdout, line)
+}
+
+// formatResult expands a subdomain into its output lines, adding any
+// requested annotations.
+func (s *SubHunter) formatResult(subdomain string) []string {
+	lines := s.formatTargets(subdomain)
+	if s.withASN {
+		if annotation := s.asnAnnotation(subdomain); annotation != "" {
+			for i := range lines {
+				lines[i] += " " + annotation
+			}
+		}
+	}
+	if s.flagSuspicious && isSuspicious(subdomain) {
+		for i := range lines {
+			lines[i] += " [SUSPICIOUS]"
+		}
+	}
+	if s.withCounts {
+		count := s.certCount(subdomain)
+		for i := range lines {
+			lines[i] = fmt.Sprintf("%s (%d)", lines[i], count)
+		}
+	}
+	if s.withID {
+		if id := s.certID(subdomain); id > 0 {
+			for i := range lines {
+				lines[i] = fmt.Sprintf("%s # crt.sh/?id=%d", lines[i], id)
+			}
+		}
+	}
+	return lines
+}
+
+// formatTargets returns the bare subdomain, or in URL mode one URL per
+// scheme/port pair.
+func (s *SubHunter) formatTargets(subdomain string) []string {
+	if len(s.scanPorts) > 0 {
+		var lines []string
+		for _, port := range s.hostOpenPorts(subdomain) {
+			lines = append(lines, fmt.Sprintf("%s:%d", s.encodeName(subdomain), port))
+		}
+		return lines
+	}
+	subdomain = s.encodeName(subdomain)
+	if !s.urls {
+		return []string{subdomain}
+	}
+
+	schemes := []string{"https"}
+	if s.bothSchemes {
+		schemes = append(schemes, "http")
+	}
+
+	var lines []string
+	for _, scheme := range schemes {
+		if len(s.ports) == 0 {
+			lines = append(lines, fmt.Sprintf("%s://%s", scheme, subdomain))
+			continue
+		}
+		for _, port := range s.ports {
+			lines = append(lines, fmt.Sprintf("%s://%s:%d", scheme, subdomain, port))
+		}
+	}
+	return lines
+}
+
+// encodeName converts a hostname to the configured output encoding, leaving
+// it untouched if the conversion fails.
+func (s *SubHunter) encodeName(name string) string {
+	var encoded string
+	var err error
+	if s.outputEncoding == encodingUnicode {
+		encoded, err = idna.ToUnicode(name)
 	} else {
-		fmt.Println(subdomain)
+		encoded, err = idna.ToASCII(name)
+	}
+	if err != nil {
+		return name
+	}
+	return encoded
+}
+
+func parsePorts(value string) ([]int, error) {
+	var ports []int
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		port, err := strconv.Atoi(field)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %q", field)
+		}
+		ports = append(ports, port)
 	}
+	return ports, nil
 }
 
 func (s *SubHunter) isValidSubdomain(subdomain string) bool {
-	if len(subdomain) == 0 || len(subdomain) > 253 {
-		return false
+	return subdomainProblem(subdomain) == ""
+}
+
+// subdomainProblem explains why subdomain is not a valid DNS name, or
+// returns "" if it is.
+func subdomainProblem(subdomain string) string {
+	if len(subdomain) == 0 {
+		return "empty"
+	}
+	if len(subdomain) > 253 {
+		return "too long"
 	}
 
 	subdomain = strings.TrimPrefix(subdomain, "*.")
 	parts := strings.Split(subdomain, ".")
 	for _, part := range parts {
-		if len(part) == 0 || len(part) > 63 {
-			return false
+		if len(part) == 0 {
+			return "empty label"
+		}
+		if len(part) > 63 {
+			return "label too long"
+		}
+		// letters, digits, inner hyphens and underscores (_dmarc, SRV
+		// names) only; a leading "*." was trimmed above, and IDNs must be
+		// in punycode
+		for _, c := range part {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return fmt.Sprintf("invalid character %q", c)
+			}
+		}
+		if part[0] == '-' || part[len(part)-1] == '-' {
+			return "label starts or ends with a hyphen"
 		}
 	}
 
-	return true
+	return ""
 }
 
-func (s *SubHunter) extractSubdomains(domain string, nameValues []string) []string {
-	subdomainSet := make(map[string]bool)
-	pattern := regexp.MustCompile(`(?i)\b(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)*` + regexp.QuoteMeta(domain) + `\b`)
+// parallelExtractThreshold is the number of certificate entries above which
+// extraction is spread across the worker pool.
+const parallelExtractThreshold = 5000
 
-	for _, nameValue := range nameValues {
-		entries := strings.Split(nameValue, "\n")
-		for _, entry := range entries {
-			matches := pattern.FindAllString(entry, -1)
-			for _, match := range matches {
-				subdomain := strings.ToLower(strings.TrimSpace(match))
-				subdomain = strings.TrimPrefix(subdomain, "*.")
+// normalizeSubdomain lowercases a hostname and strips surrounding whitespace
+// and any wildcard prefix.
+func normalizeSubdomain(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.TrimPrefix(name, "*.")
+}
 
-				if s.isValidSubdomain(subdomain) && strings.Contains(subdomain, domain) {
-					subdomainSet[subdomain] = true
-				}
-			}
-		}
+// normalizeListEntry returns the subdomain on a line of a subdomain list,
+// normalized like a result. Lists are often earlier SubHunter output, so
+// annotations are dropped and unicode names converted to punycode first
+// (see existingHostname); comment lines yield "".
+func normalizeListEntry(line string) string {
+	return normalizeSubdomain(existingHostname(line))
+}
+
+// underDomain reports whether name is domain or one of its subdomains. A
+// substring test is not enough: "notexample.com" contains "example.com".
+func underDomain(name, domain string) bool {
+	return name == domain || strings.HasSuffix(name, "."+domain)
+}
+
+// domainMatches returns the normalized hostnames under domain that pattern
+// finds in text. A match must be a whole hostname, so the "example.com" in
+// "example.com.attacker.net" or "evil-example.com" is skipped.
+func domainMatches(pattern *regexp.Regexp, text, domain string) []string {
+	isLabelByte := func(c byte) bool {
+		return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-'
 	}
 
-	subdomains := make([]string, 0, len(subdomainSet))
-	for sub := range subdomainSet {
-		subdomains = append(subdomains, sub)
+	var names []string
+	for _, loc := range pattern.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		if start > 0 && text[start-1] == '-' {
+			continue
+		}
+		if end < len(text) && (text[end] == '-' || text[end] == '.' && end+1 < len(text) && isLabelByte(text[end+1])) {
+			continue
+		}
+		if name := normalizeSubdomain(text[start:end]); underDomain(name, domain) {
+			names = append(names, name)
+		}
 	}
-	sort.Strings(subdomains)
+	return names
+}
 
-	return subdomains
+// maxDomainPatterns bounds the compiled pattern cache; when it is full the
+// cache starts over, which is cheap next to keeping every domain of a huge
+// list.
+const maxDomainPatterns = 1024
+
+// domainPattern returns the regular expression matching hostnames under
+// domain, compiling it only the first time a domain is seen.
+func (s *SubHunter) domainPattern(domain string) *regexp.Regexp {
+	s.patternMu.Lock()
+	defer s.patternMu.Unlock()
+
+	if pattern, ok := s.patterns[domain]; ok {
+		return pattern
+	}
+	if s.patterns == nil || len(s.patterns) >= maxDomainPatterns {
+		s.patterns = make(map[string]*regexp.Regexp)
+	}
+	pattern := regexp.MustCompile(`(?i)\b(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)*` + regexp.QuoteMeta(domain) + `\b`)
+	s.patterns[domain] = pattern
+	return pattern
 }
 
-func (s *SubHunter) queryAPI(domain string) ([]string, error) {
-	url := fmt.Sprintf("https://crt.sh/?q=%%.%s&output=json", domain)
-	var lastErr error
+func (s *SubHunter) extractSubdomains(domain string, results []CRTResponse) []string {
+	pattern := s.domainPattern(domain)
+	if s.noDedupe {
+		return s.extractAll(domain, pattern, results)
+	}
 
-	// RETRY LOOP
-	for attempt := 1; attempt <= s.maxRetries; attempt++ {
-		if attempt > 1 {
-			s.log("retry", fmt.Sprintf("Attempt %d/%d for", attempt, s.maxRetries), domain)
-			time.Sleep(time.Duration(attempt) * time.Second) // Backoff: 1s, 2s, 3s...
-		} else {
-			s.log("run", "Querying crt.sh API", domain)
-		}
+	// A single response is held in full anyway, so it is deduped exactly;
+	// the -low-memory bloom filter is only for the stream across domains.
+	subdomainSet := make(mapSet)
+	var subdomains []string
+	var mu sync.Mutex
 
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return nil, err
+	// Large responses are split into chunks matched in parallel; each chunk
+	// produces a partial set that is merged under the mutex.
+	workers := s.concurrency
+	if workers < 1 || len(results) < parallelExtractThreshold {
+		workers = 1
+	}
+	chunkSize := (len(results) + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for start := 0; start < len(results); start += chunkSize {
+		end := start + chunkSize
+		if end > len(results) {
+			end = len(results)
 		}
 
-		// User-Agent prevents some WAF blocks
-		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+		wg.Add(1)
+		go func(chunk []CRTResponse) {
+			defer wg.Done()
+			partial := s.extractChunk(domain, pattern, chunk)
 
-		resp, err := s.client.Do(req)
-		if err != nil {
-			lastErr = err
-			continue // Try again on connection error
-		}
-		defer resp.Body.Close()
+			mu.Lock()
+			for _, sub := range partial {
+				if subdomainSet.add(sub) {
+					subdomains = append(subdomains, sub)
+				}
+			}
+			mu.Unlock()
+		}(results[start:end])
+	}
+	wg.Wait()
 
-		if resp.StatusCode != http.StatusOK {
-			lastErr = fmt.Errorf("HTTP %d", resp.StatusCode)
-			// If it's a 502/503/504, it's a server error, so we retry.
-			// If it's 404, retrying won't help, but for crt.sh 404 usually means something broke anyway.
-			continue
-		}
+	s.sortResults(subdomains)
 
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			lastErr = err
-			continue
-		}
+	return subdomains
+}
 
-		// Check if body is HTML (crt.sh often returns HTML error pages with status 200 sometimes)
-		if strings.HasPrefix(strings.TrimSpace(string(body)), "<") {
-			lastErr = fmt.Errorf("API returned HTML instead of JSON")
-			continue
+// extractChunk returns the unique subdomains of domain found in a slice of
+// certificate entries.
+func (s *SubHunter) extractChunk(domain string, pattern *regexp.Regexp, results []CRTResponse) []string {
+	partialSet := make(mapSet)
+	var partial []string
+
+	for _, result := range results {
+		matched := false
+		var counted map[string]bool // names already counted for this entry
+		if s.countCerts {
+			counted = make(map[string]bool)
 		}
-
-		var results []CRTResponse
-		if err := json.Unmarshal(body, &results); err != nil {
-			lastErr = fmt.Errorf("JSON decode failed: %v", err)
-			continue
+		entries := strings.Split(result.NameValue, "\n")
+		for _, entry := range entries {
+			for _, subdomain := range domainMatches(pattern, entry, domain) {
+				if !s.isValidSubdomain(subdomain) {
+					continue
+				}
+				matched = true
+				s.recordMatch(subdomain, entry, result, counted)
+				if partialSet.add(subdomain) {
+					partial = append(partial, subdomain)
+				}
+			}
 		}
-
-		// If we got here, success!
-		nameValues := make([]string, len(results))
-		for i, result := range results {
-			nameValues[i] = result.NameValue
+		if matched && s.timeline {
+			s.recordIssuance(result)
+		}
+		if matched && s.caStats {
+			s.recordIssuer(result)
 		}
-		return s.extractSubdomains(domain, nameValues), nil
 	}
 
-	return nil, fmt.Errorf("max retries exceeded: %v", lastErr)
+	return partial
 }
 
-func (s *SubHunter) processDomain(domain string, showResults bool) []string {
-	domain = strings.ToLower(strings.TrimSpace(domain))
-	if domain == "" {
-		return nil
+// recordMatch records the per-subdomain annotations for a match in name, a
+// line of a certificate entry. counted holds the names already counted for
+// the entry.
+func (s *SubHunter) recordMatch(subdomain, name string, result CRTResponse, counted map[string]bool) {
+	if s.wildcardTree && strings.HasPrefix(strings.TrimSpace(name), "*.") && normalizeSubdomain(name) == subdomain {
+		s.recordWildcard(subdomain)
+	}
+	if s.withID {
+		s.recordCertID(subdomain, result.ID)
+	}
+	if s.trackFirstSeen {
+		s.recordFirstSeen(subdomain, result)
 	}
+	if s.countCerts && !counted[subdomain] {
+		counted[subdomain] = true
+		s.recordCertCount(subdomain)
+	}
+}
 
-	subdomains, err := s.queryAPI(domain)
-	if err != nil {
-		s.log("error", fmt.Sprintf("Failed to query %s", domain), err.Error())
-		return nil
+// extractAll returns every occurrence of a subdomain of domain in the
+// results, in encounter order and without deduplication, for -no-dedupe.
+func (s *SubHunter) extractAll(domain string, pattern *regexp.Regexp, results []CRTResponse) []string {
+	var all []string
+	for _, result := range results {
+		matched := false
+		var counted map[string]bool
+		if s.countCerts {
+			counted = make(map[string]bool)
+		}
+		for _, entry := range strings.Split(result.NameValue, "\n") {
+			for _, subdomain := range domainMatches(pattern, entry, domain) {
+				if !s.isValidSubdomain(subdomain) {
+					continue
+				}
+				matched = true
+				s.recordMatch(subdomain, entry, result, counted)
+				all = append(all, subdomain)
+			}
+		}
+		if matched && s.timeline {
+			s.recordIssuance(result)
+		}
+		if matched && s.caStats {
+			s.recordIssuer(result)
+		}
 	}
+	return all
+}
 
-	count := len(subdomains)
-	s.mu.Lock()
-	s.totalFound += count
-	s.mu.Unlock()
+var hostnamePattern = regexp.MustCompile(`(?i)(?:\*\.)?(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9-]{0,61}[a-z0-9]`)
 
-	if count > 0 {
-		s.log("found", fmt.Sprintf("Discovered %d subdomains", count), "")
-		if showResults {
-			for _, sub := range subdomains {
-				s.printResult(sub)
+// extractHostnames returns every valid hostname in the results regardless of
+// which domain it belongs to.
+func (s *SubHunter) extractHostnames(results []CRTResponse) []string {
+	hostSet := make(mapSet)
+	var hosts []string
+
+	for _, result := range results {
+		for _, match := range hostnamePattern.FindAllString(result.NameValue, -1) {
+			host := normalizeSubdomain(match)
+			if s.isValidSubdomain(host) && hostSet.add(host) {
+				hosts = append(hosts, host)
 			}
 		}
-	} else {
-		s.log("warn", "No subdomains found", "")
 	}
 
-	return subdomains
+	s.sortResults(hosts)
+	return hosts
 }
 
-func (s *SubHunter) processDomainsFromFile(filename string, concurrent bool) []string {
-	file, err := os.Open(filename)
-	if err != nil {
-		s.log("error", "Cannot read file", err.Error())
-		return nil
+// siblingHostnames returns the hostnames outside domain that appear on
+// certificates also naming domain or one of its subdomains. Multi-SAN
+// certificates often cover related infrastructure under other apexes.
+func (s *SubHunter) siblingHostnames(domain string, results []CRTResponse) []string {
+	var matching []CRTResponse
+	for _, result := range results {
+		for _, name := range strings.Split(result.NameValue, "\n") {
+			name = normalizeSubdomain(name)
+			if underDomain(name, domain) {
+				matching = append(matching, result)
+				break
+			}
+		}
 	}
-	defer file.Close()
 
-	var domains []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		domain := strings.TrimSpace(scanner.Text())
-		if domain != "" {
-			domains = append(domains, domain)
+	var siblings []string
+	for _, host := range s.extractHostnames(matching) {
+		if !underDomain(host, domain) {
+			siblings = append(siblings, host)
 		}
 	}
+	return siblings
+}
 
-	s.log("info", fmt.Sprintf("Loaded %d domains from", len(domains)), filename)
+// printSANDomains prints the -include-san-domains hostnames after the
+// results, under a comment header that sets them apart.
+func (s *SubHunter) printSANDomains() {
+	s.printLine(fmt.Sprintf("# %d hostnames under other domains on the same certificates", len(s.sanDomains)))
+	for _, host := range s.sanDomains {
+		s.printLine(host)
+	}
+}
 
-	if concurrent {
-		s.log("info", fmt.Sprintf("Using %d concurrent workers", s.concurrency), "")
+// recordCertID keeps the lowest (oldest) crt.sh certificate ID seen for a
+// subdomain so results can be traced back to a certificate.
+func (s *SubHunter) recordCertID(subdomain string, id int64) {
+	if id <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, ok := s.certIDs[subdomain]; !ok || id < current {
+		s.certIDs[subdomain] = id
 	}
+}
 
-	allSubdomains := make(map[string]bool)
-	var mu sync.Mutex
+func (s *SubHunter) certID(subdomain string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.certIDs[subdomain]
+}
+
+// recordCertCount counts one more certificate entry naming subdomain.
+func (s *SubHunter) recordCertCount(subdomain string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.certCounts[subdomain]++
+}
+
+func (s *SubHunter) certCount(subdomain string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.certCounts[subdomain]
+}
 
-	if concurrent && len(domains) > 1 {
-		semaphore := make(chan struct{}, s.concurrency)
-		var wg sync.WaitGroup
+// trimQueryPrefix strips leading dots and wildcards from a target, so a
+// pasted ".example.com", "*.example.com" or "%.example.com" does not turn
+// into a query like "%..example.com" or "%.%.example.com", which matches
+// nothing. URL-encoded wildcards ("%25", "%2A") copied from a crt.sh link
+// are stripped too.
+func trimQueryPrefix(domain string) string {
+	for {
+		switch {
+		case len(domain) >= 3 && (domain[:3] == "%25" || strings.EqualFold(domain[:3], "%2a")):
+			domain = domain[3:]
+		case strings.HasPrefix(domain, ".") || strings.HasPrefix(domain, "%") || strings.HasPrefix(domain, "*"):
+			domain = domain[1:]
+		default:
+			return domain
+		}
+	}
+}
 
-		for i, domain := range domains {
-			wg.Add(1)
-			go func(idx int, d string) {
-				defer wg.Done()
-				semaphore <- struct{}{}
-				defer func() { <-semaphore }()
+// crtQueryPath returns the crt.sh JSON search for everything under domain.
+// With -cn-only it searches certificate common names only.
+func (s *SubHunter) crtQueryPath(domain string) string {
+	return fmt.Sprintf("/?%s=%%.%s&output=json", s.queryParam(), domain)
+}
 
-				subs := s.processDomain(d, false)
+// crtBareQueryPath returns the crt.sh JSON search for domain without the
+// wildcard prefix, for -try-alt-query.
+func (s *SubHunter) crtBareQueryPath(domain string) string {
+	return fmt.Sprintf("/?%s=%s&output=json", s.queryParam(), domain)
+}
 
-				mu.Lock()
-				for _, sub := range subs {
-					allSubdomains[sub] = true
-				}
-				mu.Unlock()
+// queryParam is the crt.sh search parameter: q matches any identity of a
+// certificate, CN only its common name.
+func (s *SubHunter) queryParam() string {
+	if s.cnOnly {
+		return "CN"
+	}
+	return "q"
+}
+
+func (s *SubHunter) queryAPI(domain string) ([]string, error) {
+	if !s.inScope(domain) {
+		return nil, fmt.Errorf("%s: %w", domain, ErrOutOfScope)
+	}
+	if s.rawOut != nil {
+		return nil, s.saveRaw(domain)
+	}
+	if cached, ok := s.memCache.get(domain); ok {
+		return cached, nil
+	}
 
-				s.log("success", fmt.Sprintf("[%d/%d] %s", idx+1, len(domains), d), fmt.Sprintf("%d found", len(subs)))
-			}(i, domain)
+	path := s.crtQueryPath(domain)
+	onEntry := s.headPreview(domain)
+	if s.streamLive {
+		onEntry = s.livePreview(domain)
+	}
+	results, err := s.fetchCertificates(path, domain, onEntry)
+	if err != nil && s.fallbackText && errors.Is(err, ErrHTMLResponse) {
+		s.log("warn", "JSON endpoint keeps returning HTML, falling back to the text results for", domain)
+		results, err = s.fetchTextResults(domain)
+	}
+	if err == nil && len(results) == 0 && s.tryAltQuery {
+		// crt.sh sometimes indexes certificates under the bare name only
+		s.log("info", "No results, retrying without the wildcard prefix for", domain)
+		alt, altErr := s.fetchCertificates(s.crtBareQueryPath(domain), domain, onEntry)
+		if altErr != nil {
+			s.log("warn", "Bare query failed", altErr.Error())
+		} else if len(alt) > 0 {
+			s.log("success", fmt.Sprintf("Bare query found %d certificates for", len(alt)), domain)
+			results = alt
 		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	subdomains := s.filterResults(s.extractSubdomains(domain, results))
+	s.memCache.put(domain, subdomains)
+	if s.includeSANDomains {
+		siblings := s.siblingHostnames(domain, results)
+		s.mu.Lock()
+		s.sanDomains = append(s.sanDomains, siblings...)
+		s.mu.Unlock()
+	}
+	return subdomains, nil
+}
 
-		wg.Wait()
-	} else {
-		for i, domain := range domains {
-			s.log("run", fmt.Sprintf("[%d/%d] Processing", i+1, len(domains)), domain)
-			subs := s.processDomain(domain, false)
+// headPreview returns the -head callback for a domain query: it reports the
+// first matching name seen in the run as soon as its certificate has been
+// decoded, before the rest of the response has arrived.
+func (s *SubHunter) headPreview(domain string) func(CRTResponse) {
+	if !s.head {
+		return nil
+	}
+	return func(entry CRTResponse) {
+		for _, name := range strings.Split(entry.NameValue, "\n") {
+			name = normalizeSubdomain(name)
+			if !underDomain(name, domain) {
+				continue
+			}
+			if !s.isValidSubdomain(name) {
+				continue
+			}
 
-			for _, sub := range subs {
-				allSubdomains[sub] = true
+			s.mu.Lock()
+			first := !s.headShown
+			s.headShown = true
+			s.mu.Unlock()
+			if first {
+				s.log("found", "First result (still downloading)", name)
 			}
+			return
 		}
 	}
+}
 
-	result := make([]string, 0, len(allSubdomains))
-	for sub := range allSubdomains {
-		result = append(result, sub)
+// livePreview returns the -unsorted callback for a domain query: it prints
+// each subdomain as soon as the first certificate naming it has been
+// decoded, instead of after the whole response.
+func (s *SubHunter) livePreview(domain string) func(CRTResponse) {
+	pattern := s.domainPattern(domain)
+	return func(entry CRTResponse) {
+		for _, subdomain := range domainMatches(pattern, entry.NameValue, domain) {
+			if !s.isValidSubdomain(subdomain) {
+				continue
+			}
+			if len(s.filterResults([]string{subdomain})) == 0 {
+				continue
+			}
+			s.printLive(subdomain)
+		}
 	}
-	sort.Strings(result)
+}
 
-	s.totalFound = len(result)
-	return result
+// printLive prints a subdomain unless it was already printed live.
+func (s *SubHunter) printLive(subdomain string) {
+	s.mu.Lock()
+	if s.liveSeen == nil {
+		s.liveSeen = make(mapSet)
+	}
+	fresh := s.liveSeen.add(subdomain)
+	s.mu.Unlock()
+	if fresh {
+		s.printResult(subdomain)
+	}
 }
 
-func (s *SubHunter) saveToFile(subdomains []string, filename string) error {
-	file, err := os.Create(filename)
+// fetchTextResults queries crt.sh's regular (non-JSON) search page and wraps
+// it as a single pseudo-certificate so the extraction regex can pull
+// hostnames out of the markup. This is looser than the JSON API and is only
+// used as a last resort.
+func (s *SubHunter) fetchTextResults(domain string) ([]CRTResponse, error) {
+	url := s.endpointOrder()[0] + fmt.Sprintf("/?q=%%.%s", domain)
+	req, err := s.newCRTRequest(url)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer file.Close()
 
-	writer := bufio.NewWriter(file)
-	for _, sub := range subdomains {
-		fmt.Fprintln(writer, sub)
+	resp, body, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("text fallback: %w", ErrHTTPStatus{resp.StatusCode})
 	}
-	writer.Flush()
 
-	s.log("success", "Saved output to", filename)
-	return nil
+	text := strings.NewReplacer("<BR>", "\n", "<br>", "\n").Replace(string(body))
+	return []CRTResponse{{NameValue: text}}, nil
 }
 
-func (s *SubHunter) printSummary(elapsed time.Duration) {
-	if !s.silent {
-		fmt.Printf("\n%s%s%s\n", pink, strings.Repeat("━", 60), reset)
-		fmt.Printf("%s%s[SUMMARY]%s\n", pink, bold, reset)
-		fmt.Printf("%s%s%s\n", pink, strings.Repeat("━", 60), reset)
-		fmt.Printf("  Total Subdomains: %s%s%d%s\n", pink, bold, s.totalFound, reset)
-		fmt.Printf("  Execution Time:   %s%s%.2fs%s\n", pink, bold, elapsed.Seconds(), reset)
-		fmt.Printf("%s%s%s\n\n", pink, strings.Repeat("━", 60), reset)
+// querySHA256 looks up a single certificate by its SHA-256 fingerprint and
+// returns every hostname it covers, without restricting them to an apex.
+func (s *SubHunter) querySHA256(hash string) ([]string, error) {
+	path := fmt.Sprintf("/?sha256=%s&output=json", hash)
+	results, err := s.fetchCertificates(path, hash, nil)
+	if err != nil {
+		return nil, err
 	}
+	return s.filterResults(s.extractHostnames(results)), nil
 }
 
-func main() {
-	domain := flag.String("d", "", "target domain")
-	domainList := flag.String("l", "", "file with domain list")
-	output := flag.String("o", "", "output file path")
-	// Changed default timeout to 60s
-	timeout := flag.Int("t", 60, "timeout in seconds")
-	concurrency := flag.Int("c", 5, "concurrent workers")
-	concurrent := flag.Bool("concurrent", false, "enable concurrent mode")
-	silent := flag.Bool("silent", false, "silent mode (only results)")
-	showVersion := flag.Bool("version", false, "show version")
+// newCRTRequest builds a GET request to crt.sh with the headers every query
+// carries.
+func (s *SubHunter) newCRTRequest(url string) (*http.Request, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
 
-	flag.Parse()
+	s.noteSource(req.URL.Scheme + "://" + req.URL.Host + req.URL.Path)
 
-	if *showVersion {
-		fmt.Printf("SubHunter v%s\n", version)
-		os.Exit(0)
+	// User-Agent prevents some WAF blocks
+	req.Header.Set("User-Agent", userAgent)
+	if s.basicAuthUser != "" {
+		req.SetBasicAuth(s.basicAuthUser, s.basicAuthPass)
 	}
-
-	if !*silent {
-		fmt.Printf("%s%s%s%s", pink, bold, fmt.Sprintf(banner, version), reset)
+	if s.apiToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiToken)
 	}
+	return req, nil
+}
 
-	if *domain == "" && *domainList == "" {
-		fmt.Printf("%s[ERR]%s Specify -d/--domain or -l/--list\n\n", pink, reset)
-		flag.Usage()
-		os.Exit(1)
-	}
+// apiTokenEnv is read for the API token when -api-token is not given.
+const apiTokenEnv = "SUBHUNTER_API_TOKEN"
 
-	if *domain != "" && *domainList != "" {
-		fmt.Printf("%s[ERR]%s Cannot use -d and -l together\n\n", pink, reset)
-		os.Exit(1)
-	}
+// tokenConcurrency is the default number of workers when an API token is
+// set: an authenticated tier allows a higher request rate.
+const tokenConcurrency = 10
 
-	hunter := NewSubHunter(*timeout, *concurrency, *silent)
+// basicAuthEnv is read for the crt.sh credentials when -basic-auth is not
+// given, so they need not appear in process listings.
+const basicAuthEnv = "SUBHUNTER_BASIC_AUTH"
 
-	if !*silent {
-		fmt.Printf("%s%s%s\n", pink, strings.Repeat("━", 60), reset)
-		fmt.Printf("%s%s[CONFIGURATION]%s\n", pink, bold, reset)
-		fmt.Printf("%s%s%s\n", pink, strings.Repeat("━", 60), reset)
+// parseBasicAuth splits a "user:pass" credential.
+func parseBasicAuth(value string) (user, pass string, err error) {
+	user, pass, ok := strings.Cut(value, ":")
+	if !ok || user == "" {
+		return "", "", fmt.Errorf("invalid basic auth credentials (expected user:pass)")
+	}
+	return user, pass, nil
+}
 
-		target := *domain
-		if target == "" {
-			target = *domainList
-		}
-		outputStr := "stdout"
-		if *output != "" {
-			outputStr = *output
-		}
+// do performs req while holding a network slot and returns the response
+// with its body fully read.
+func (s *SubHunter) do(req *http.Request) (*http.Response, []byte, error) {
+	var body []byte
+	resp, err := s.send(req, func(resp *http.Response) error {
+		var err error
+		body, err = io.ReadAll(resp.Body)
+		return err
+	})
+	return resp, body, err
+}
 
-		fmt.Printf("  Target:       %s%s%s\n", pink, target, reset)
-		fmt.Printf("  Output:       %s%s%s\n", pink, outputStr, reset)
-		fmt.Printf("  Timeout:      %s%ds%s\n", pink, *timeout, reset)
+// send performs req while holding a network slot and passes the response to
+// handle, which may consume the body as it arrives. The body is closed once
+// handle returns.
+func (s *SubHunter) send(req *http.Request, handle func(*http.Response) error) (*http.Response, error) {
+	s.acquireNet()
+	defer s.releaseNet()
 
-		if *domainList != "" && *concurrent {
-			fmt.Printf("  Workers:      %s%d%s\n", pink, *concurrency, reset)
-		}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
-		fmt.Printf("%s%s%s\n\n", pink, strings.Repeat("━", 60), reset)
+	if s.maxResponseSize > 0 {
+		resp.Body = &limitedBody{ReadCloser: resp.Body, left: s.maxResponseSize, limit: s.maxResponseSize}
 	}
+	return resp, handle(resp)
+}
 
-	start := time.Now()
-	var subdomains []string
+// limitedBody fails reads with ErrResponseTooLarge once more than limit
+// bytes have been read, instead of truncating silently like io.LimitReader.
+type limitedBody struct {
+	io.ReadCloser
+	left, limit int64
+}
 
-	if *domainList != "" {
-		subdomains = hunter.processDomainsFromFile(*domainList, *concurrent)
-	} else {
-		if !*silent {
-			hunter.log("info", "Target domain", *domain)
-		}
-		subdomains = hunter.processDomain(*domain, true)
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.left < 0 {
+		return 0, ErrResponseTooLarge{b.limit}
+	}
+	// read one byte past the limit to tell "exactly limit" from "more"
+	if int64(len(p)) > b.left+1 {
+		p = p[:b.left+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.left -= int64(n)
+	if b.left < 0 {
+		return 0, ErrResponseTooLarge{b.limit}
+	}
+	return n, err
+}
+
+// decodeCertificates decodes a crt.sh JSON array entry by entry, calling
+// onEntry (if set) for each certificate as soon as it has been read.
+func decodeCertificates(r io.Reader, onEntry func(CRTResponse)) ([]CRTResponse, error) {
+	reader := bufio.NewReader(r)
+
+	// crt.sh sometimes answers with an HTML error page and status 200
+	for {
+		c, err := reader.ReadByte()
+		if err != nil {
+			return nil, ErrDecode{err}
+		}
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+			continue
+		}
+		reader.UnreadByte()
+		if c == '<' {
+			return nil, ErrHTMLResponse
+		}
+		break
+	}
+
+	decoder := json.NewDecoder(reader)
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, ErrDecode{err}
+	}
+	if token == nil {
+		return nil, nil // "null": no certificates
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, ErrDecode{errors.New("expected an array")}
+	}
+
+	var results []CRTResponse
+	for decoder.More() {
+		var entry CRTResponse
+		if err := decoder.Decode(&entry); err != nil {
+			return nil, ErrDecode{err}
+		}
+		if onEntry != nil {
+			onEntry(entry)
+		}
+		results = append(results, entry)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, ErrDecode{err}
+	}
+	return results, nil
+}
+
+// fetchCertificates downloads and decodes a crt.sh JSON response for path,
+// a query on the crt.sh endpoints, retrying on transient failures. target
+// is only used for logging; onEntry is passed to decodeCertificates.
+func (s *SubHunter) fetchCertificates(path, target string, onEntry func(CRTResponse)) ([]CRTResponse, error) {
+	var results []CRTResponse
+	sawEmpty := false
+	if s.cnOnly && onEntry != nil {
+		inner := onEntry
+		onEntry = func(entry CRTResponse) {
+			inner(commonNameOnly(entry))
+		}
+	}
+	err := s.fetchWithRetry(path, target, func(body io.Reader) error {
+		var err error
+		results, err = decodeCertificates(body, onEntry)
+		if s.cnOnly {
+			for i := range results {
+				results[i] = commonNameOnly(results[i])
+			}
+		}
+		if err == nil && len(results) == 0 && s.retryOnEmpty {
+			// crt.sh sometimes answers with nothing, then the full set
+			// moments later
+			sawEmpty = true
+			return ErrEmptyResponse
+		}
+		return err
+	})
+	if errors.Is(err, ErrEmptyResponse) {
+		return nil, nil // still empty after every retry
+	}
+	if err == nil && sawEmpty {
+		s.log("success", fmt.Sprintf("Retry after an empty response found %d certificates for", len(results)), target)
+	}
+	return results, err
+}
+
+// commonNameOnly drops the SANs of a certificate for -cn-only, so only
+// its common name is matched.
+func commonNameOnly(entry CRTResponse) CRTResponse {
+	entry.NameValue = entry.CommonName
+	return entry
+}
+
+// fetchWithRetry queries path on the crt.sh endpoints and passes each
+// successful response body to handle, retrying on transient failures and
+// on errors from handle. Within an attempt every endpoint is tried in turn
+// before the attempt counts as failed.
+func (s *SubHunter) fetchWithRetry(path, target string, handle func(io.Reader) error) error {
+	var lastErr error
+	var retryAfter time.Duration
+
+	// RETRY LOOP
+	for attempt := 1; attempt <= s.maxRetries; attempt++ {
+		if attempt > 1 {
+			if !s.takeRetry() {
+				break
+			}
+			s.log("retry", fmt.Sprintf("Attempt %d/%d for", attempt, s.maxRetries), target)
+			wait := s.backoff(attempt)
+			if retryAfter > 0 {
+				// The server told us how long to back off, so honor that instead
+				wait = retryAfter
+				retryAfter = 0
+			}
+			if !s.wait(wait) {
+				return fmt.Errorf("%w, last error: %v", ErrInterrupted, lastErr)
+			}
+		} else {
+			s.log("run", "Querying crt.sh API", target)
+		}
+
+		if !s.awaitCooldown() {
+			return fmt.Errorf("%w, last error: %v", ErrInterrupted, lastErr)
+		}
+
+		endpoints := s.endpointOrder()
+		for i, endpoint := range endpoints {
+			req, err := s.newCRTRequest(endpoint + path)
+			if err != nil {
+				return err
+			}
+
+			resp, err := s.send(req, func(resp *http.Response) error {
+				if resp.StatusCode != http.StatusOK {
+					return ErrHTTPStatus{resp.StatusCode}
+				}
+				return handle(resp.Body)
+			})
+			if err == nil {
+				// If we got here, success!
+				s.endpointAnswered(endpoint)
+				return nil
+			}
+
+			var tooLarge ErrResponseTooLarge
+			if errors.As(err, &tooLarge) {
+				return tooLarge
+			}
+			lastErr = err
+			if resp != nil {
+				delay, ok := s.retryAfterDelay(resp)
+				if ok {
+					s.log("warn", fmt.Sprintf("Server asked to retry after %s for", delay), target)
+					retryAfter = delay
+				}
+				if resp.StatusCode == http.StatusTooManyRequests {
+					if !ok {
+						delay = s.cooldown
+					}
+					if s.cooldown > 0 {
+						s.startCooldown(delay)
+					}
+				}
+			}
+			if i < len(endpoints)-1 {
+				s.log("warn", fmt.Sprintf("%s failed (%v), trying the next endpoint for", endpoint, err), target)
+			}
+		}
+		// Connection errors, 5xx responses, HTML error pages and
+		// truncated JSON are all retried.
+		// If it's 404, retrying won't help, but for crt.sh 404 usually means something broke anyway.
+	}
+
+	return ErrMaxRetries{lastErr}
+}
+
+// enumerateDomain normalizes a target domain, queries it and counts its
+// results for the summary. It returns the domain as queried, "" if nothing
+// was left to query.
+func (s *SubHunter) enumerateDomain(domain string) (string, []string, error) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if trimmed := trimQueryPrefix(domain); trimmed != domain {
+		s.log("warn", fmt.Sprintf("Normalized %q to", domain), trimmed)
+		domain = trimmed
+	}
+	if domain == "" {
+		return "", nil, nil
+	}
+
+	subdomains, err := s.queryAPI(domain)
+	if err != nil || s.rawOut != nil {
+		return domain, nil, err
+	}
+
+	s.mu.Lock()
+	s.totalFound += len(subdomains)
+	s.mu.Unlock()
+	s.recordDomainCount(domain, len(subdomains))
+	return domain, subdomains, nil
+}
+
+func (s *SubHunter) processDomain(domain string, showResults bool) []string {
+	domain, subdomains, err := s.enumerateDomain(domain)
+	if err != nil {
+		s.log("error", fmt.Sprintf("Failed to query %s", domain), err.Error())
+		return nil
+	}
+	if domain == "" {
+		return nil
+	}
+
+	if s.rawOut != nil {
+		s.log("success", "Saved raw response for", domain)
+		return nil
+	}
+
+	count := len(subdomains)
+	if count > 0 {
+		s.log("found", fmt.Sprintf("Discovered %d subdomains", count), "")
+		if showResults {
+			for _, sub := range subdomains {
+				if s.streamLive {
+					// anything not printed while downloading, e.g. from
+					// the text fallback
+					s.printLive(sub)
+				} else {
+					s.printResult(sub)
+				}
+			}
+		}
+	} else {
+		s.log("warn", "No subdomains found", "")
+	}
+
+	return subdomains
+}
+
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// normalizeSHA256 accepts a hex fingerprint with optional colon separators
+// and returns it in the lowercase form crt.sh expects.
+func normalizeSHA256(hash string) (string, error) {
+	hash = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(hash), ":", ""))
+	if !sha256Pattern.MatchString(hash) {
+		return "", fmt.Errorf("invalid SHA-256 fingerprint %q (expected 64 hex characters)", hash)
+	}
+	return hash, nil
+}
+
+func (s *SubHunter) processCertificate(hash string, showResults bool) []string {
+	hosts, err := s.querySHA256(hash)
+	if err != nil {
+		s.log("error", "Failed to query certificate", err.Error())
+		return nil
+	}
+
+	s.totalFound = len(hosts)
+	if len(hosts) == 0 {
+		s.log("warn", "No hostnames found in certificate", "")
+		return nil
+	}
+
+	s.log("found", fmt.Sprintf("Certificate covers %d hostnames", len(hosts)), "")
+	if showResults {
+		for _, host := range hosts {
+			s.printResult(host)
+		}
+	}
+	return hosts
+}
+
+// processDomainsFromFiles loads the domain lists, dropping domains that
+// appear in more than one of them, and enumerates the combined list.
+func (s *SubHunter) processDomainsFromFiles(filenames []string, concurrent bool) []string {
+	var domains []string
+	seen := make(map[string]bool)
+	for _, filename := range filenames {
+		lines, encoding, err := readLinesEncoding(filename)
+		if err != nil {
+			s.log("error", "Cannot read file", err.Error())
+			continue
+		}
+		if encoding != "" {
+			s.log("warn", fmt.Sprintf("Converted %s list to UTF-8:", encoding), filename)
+		}
+		s.log("info", fmt.Sprintf("Loaded %d domains from", len(lines)), filename)
+
+		for _, line := range lines {
+			d, meta, err := parseListEntry(line)
+			if err != nil {
+				s.log("warn", "Skipping list entry", err.Error())
+				continue
+			}
+			if meta != nil {
+				s.setInputMeta(d, meta)
+			}
+			key := strings.ToLower(d)
+			if !seen[key] {
+				seen[key] = true
+				domains = append(domains, d)
+			}
+		}
+	}
+
+	if len(filenames) > 1 {
+		s.log("info", fmt.Sprintf("Loaded %d unique domains from %d files", len(domains), len(filenames)), "")
+	}
+	if len(domains) == 0 {
+		return nil
+	}
+
+	return s.processDomains(domains, concurrent)
+}
+
+// skipCoveredDomains drops input domains that lie under another input
+// domain. crt.sh's "%.example.com" search already returns everything under
+// corp.example.com, so querying both would only repeat the work.
+func (s *SubHunter) skipCoveredDomains(domains []string) []string {
+	inputs := make(map[string]bool, len(domains))
+	for _, d := range domains {
+		inputs[strings.ToLower(strings.TrimSpace(d))] = true
+	}
+
+	var kept []string
+	for _, d := range domains {
+		name := strings.ToLower(strings.TrimSpace(d))
+		if parent := coveringDomain(name, inputs); parent != "" {
+			s.log("info", fmt.Sprintf("Skipping %s, already covered by", name), parent)
+			continue
+		}
+		kept = append(kept, d)
+	}
+	return kept
+}
+
+// coveringDomain returns the closest proper parent of name found in
+// domains, or "" if there is none.
+func coveringDomain(name string, domains map[string]bool) string {
+	for i := strings.Index(name, "."); i >= 0; i = strings.Index(name, ".") {
+		name = name[i+1:]
+		if domains[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+// wait sleeps for d and reports whether it ran to completion, returning
+// false as soon as the run is interrupted.
+func (s *SubHunter) wait(d time.Duration) bool {
+	if s.ctx.Err() != nil {
+		return false
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+// capDomain truncates one domain's results to -per-domain-limit before
+// they are merged with the other domains', so a single huge domain cannot
+// swamp a list run.
+func (s *SubHunter) capDomain(domain string, subdomains []string) []string {
+	if s.perDomainLimit <= 0 || len(subdomains) <= s.perDomainLimit {
+		return subdomains
+	}
+	s.log("warn", fmt.Sprintf("Keeping %d of %d subdomains (-per-domain-limit) for", s.perDomainLimit, len(subdomains)), domain)
+	return subdomains[:s.perDomainLimit]
+}
+
+// rampDelay sleeps for a random part of the -ramp window so that concurrent
+// workers do not all hit crt.sh at the same moment.
+func (s *SubHunter) rampDelay() {
+	if s.ramp <= 0 {
+		return
+	}
+	s.wait(time.Duration(randomInt63n(int64(s.ramp))))
+}
+
+// processDomains enumerates several domains, sequentially or with the worker
+// pool, and returns the merged unique results.
+func (s *SubHunter) processDomains(domains []string, concurrent bool) []string {
+	domains = s.scopedDomains(domains)
+	if s.dedupScope != dedupPerDomain {
+		// per-domain keeps every input's own results, so covered domains
+		// are queried too
+		domains = s.skipCoveredDomains(domains)
+	}
+	if s.onlyResolvableApex {
+		domains = s.resolvableApexes(domains)
+	}
+
+	if concurrent {
+		s.log("info", fmt.Sprintf("Using %d concurrent workers", s.concurrency), "")
+	}
+
+	seen := s.newSeenSet()
+	var result []string
+	var mu sync.Mutex
+
+	// In low-memory mode results are streamed out by the writer goroutine
+	// as soon as they are seen instead of being retained for a final
+	// sorted list.
+	var writer *resultWriter
+	if s.lowMemory {
+		writer = s.startResultWriter(seen)
+	}
+	collect := func(domain string, subs []string) {
+		if writer != nil {
+			writer.results <- streamedBatch{domain: domain, names: subs}
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, sub := range subs {
+			if seen.add(s.dedupKey(domain, sub)) || s.noDedupe {
+				result = append(result, sub)
+			}
+		}
+	}
+
+	batchSize := len(domains)
+	if s.batchSize > 0 && s.batchSize < batchSize {
+		batchSize = s.batchSize
+	}
+	started := 0 // workers that have taken their first job
+
+	for start := 0; start < len(domains); start += batchSize {
+		if start > 0 {
+			s.log("info", fmt.Sprintf("Batch done, pausing %s before domain %d/%d", s.batchPause, start+1, len(domains)), "")
+			if !s.wait(s.batchPause) {
+				s.log("warn", fmt.Sprintf("Interrupted, skipping the remaining %d domains", len(domains)-start), "")
+				break
+			}
+		}
+		end := min(start+batchSize, len(domains))
+
+		if concurrent && len(domains) > 1 {
+			semaphore := make(chan struct{}, s.concurrency)
+			var wg sync.WaitGroup
+
+			finish := func(idx int, subs []string) {
+				collect(domains[idx], subs)
+				s.log("success", fmt.Sprintf("[%d/%d] %s", idx+1, len(domains), domains[idx]), fmt.Sprintf("%d found", len(subs)))
+			}
+			// With -ordered-output, finished domains wait in a reorder
+			// buffer until every earlier domain is done.
+			var orderMu sync.Mutex
+			done := make(map[int][]string)
+			next := start
+
+			for i := start; i < end; i++ {
+				wg.Add(1)
+				go func(idx int, d string) {
+					defer wg.Done()
+					semaphore <- struct{}{}
+					defer func() { <-semaphore }()
+
+					mu.Lock()
+					first := started < s.concurrency
+					started++
+					mu.Unlock()
+					if first {
+						s.rampDelay()
+					}
+
+					subs := s.capDomain(d, s.processDomain(d, false))
+					if !s.orderedOutput {
+						finish(idx, subs)
+						return
+					}
+
+					orderMu.Lock()
+					defer orderMu.Unlock()
+					done[idx] = subs
+					for {
+						ready, ok := done[next]
+						if !ok {
+							break
+						}
+						delete(done, next)
+						finish(next, ready)
+						next++
+					}
+				}(i, domains[i])
+			}
+
+			wg.Wait()
+		} else {
+			for i := start; i < end; i++ {
+				s.log("run", fmt.Sprintf("[%d/%d] Processing", i+1, len(domains)), domains[i])
+				subs := s.capDomain(domains[i], s.processDomain(domains[i], false))
+				collect(domains[i], subs)
+			}
+		}
+	}
+
+	if writer != nil {
+		s.totalFound = writer.close()
+		return nil
+	}
+
+	s.sortResults(result)
+
+	s.totalFound = len(result)
+	return result
+}
+
+// emit writes a single result to the stream writer if one is configured,
+// otherwise to the terminal.
+func (s *SubHunter) emit(subdomain string) {
+	s.syslogSend("found " + subdomain)
+	if s.stream != nil {
+		s.writeStreamed(s.stream, subdomain)
+	} else if s.jsonOutput {
+		s.writeStreamed(stdout, subdomain)
+	} else {
+		s.printResult(subdomain)
+	}
+}
+
+// readLines returns the non-empty, trimmed lines of a file.
+func readLines(filename string) ([]string, error) {
+	lines, _, err := readLinesEncoding(filename)
+	return lines, err
+}
+
+// readLinesEncoding is readLines that also returns the encoding the file
+// was transcoded from, or "" for UTF-8 (see decodeText).
+func readLinesEncoding(filename string) ([]string, string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	var input io.Reader = bufio.NewReader(file)
+	encoding := ""
+	// only files with a byte order mark are read whole to be converted
+	if bom, _ := input.(*bufio.Reader).Peek(3); len(bom) >= 2 && (bom[0] == 0xEF || bom[0] == 0xFF || bom[0] == 0xFE) {
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, "", err
+		}
+		data, encoding = decodeText(data)
+		input = bytes.NewReader(data)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, encoding, scanner.Err()
+}
+
+// saveToFiles writes subdomains to every output, continuing past failures,
+// and returns the combined error.
+func (s *SubHunter) saveToFiles(subdomains []string, outputs []string) error {
+	var errs []error
+	for _, output := range outputs {
+		if err := s.saveToFile(subdomains, output); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", output, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *SubHunter) saveToFile(subdomains []string, filename string) error {
+	if s.merge && !isRemoteOutput(filename) {
+		return s.mergeToFile(subdomains, filename)
+	}
+	return s.writeFile(subdomains, filename)
+}
+
+func (s *SubHunter) writeFile(subdomains []string, filename string) error {
+	out, err := s.openOutput(filename)
+	if err != nil {
+		return err
+	}
+
+	format := s.formatFor(filename)
+	if s.headerTarget != "" && format == formatText {
+		s.writeHeaderComment(out, len(subdomains))
+	}
+	if err := s.writeResultsAs(out, subdomains, format); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	s.log("success", "Saved output to", filename)
+	return nil
+}
+
+func (s *SubHunter) printSummary(subdomains []string, elapsed time.Duration) {
+	if s.quietSummary {
+		fmt.Fprintln(stdout, s.totalFound)
+		return
+	}
+	if s.silent {
+		return
+	}
+	stats := s.summaryStats(subdomains, elapsed)
+
+	if s.jsonOutput {
+		encoder := json.NewEncoder(stdout)
+		if s.pretty {
+			encoder.SetIndent("", "  ")
+		}
+		encoder.Encode(map[string]SummaryStats{"summary": stats})
+		return
+	}
+
+	fmt.Fprintf(stdout, "\n%s%s%s\n", pink, strings.Repeat("━", 60), reset)
+	fmt.Fprintf(stdout, "%s%s[SUMMARY]%s\n", pink, bold, reset)
+	fmt.Fprintf(stdout, "%s%s%s\n", pink, strings.Repeat("━", 60), reset)
+	fmt.Fprintf(stdout, "  Total Subdomains: %s%s%d%s\n", pink, bold, stats.Total, reset)
+	if stats.Apexes > 0 {
+		fmt.Fprintf(stdout, "  Apex Domains:     %s%s%d%s\n", pink, bold, stats.Apexes, reset)
+		fmt.Fprintf(stdout, "  Avg per Apex:     %s%s%.1f%s\n", pink, bold, stats.AvgPerApex, reset)
+		fmt.Fprintf(stdout, "  Deepest Depth:    %s%s%d%s\n", pink, bold, stats.MaxDepth, reset)
+		fmt.Fprintf(stdout, "  Longest Name:     %s%s%s%s (%d chars)\n", pink, bold, stats.LongestName, reset, stats.LongestNameSize)
+	}
+	if stats.CacheHits > 0 {
+		fmt.Fprintf(stdout, "  Cache Hits:       %s%s%d%s (%d misses)\n", pink, bold, stats.CacheHits, reset, stats.CacheMisses)
+	}
+	if s.caStats {
+		fmt.Fprintf(stdout, "  Top CAs:\n")
+		if len(stats.TopCAs) == 0 {
+			fmt.Fprintf(stdout, "    No issuers found\n")
+		}
+		for _, ca := range stats.TopCAs {
+			fmt.Fprintf(stdout, "    %s%5d%s  %s\n", pink, ca.Certificates, reset, ca.CA)
+		}
+	}
+	fmt.Fprintf(stdout, "  Execution Time:   %s%s%.2fs%s\n", pink, bold, stats.ElapsedSeconds, reset)
+	fmt.Fprintf(stdout, "%s%s%s\n\n", pink, strings.Repeat("━", 60), reset)
+}
+
+// runEnum implements the enum command: subdomain enumeration from
+// certificate transparency logs.
+func runEnum(args []string) {
+	fs := newFlagSet("enum", "[enum] [flags]")
+	domain := fs.String("d", "", "target domain (or comma-separated domains)")
+	var domainLists listFlag
+	fs.Var(&domainLists, "l", "file with domain list (repeatable or comma-separated)")
+	// Changed default timeout to 60s
+	timeout := fs.Int("t", defaultTimeout, "timeout in seconds (0 = no timeout)")
+	concurrency := fs.Int("c", 5, "concurrent workers (domains processed in parallel)")
+	perDomainLimit := fs.Int("per-domain-limit", 0, "with several domains, keep at most this many subdomains from each domain (0 = no limit)")
+	batchSize := fs.Int("batch-size", 0, "process -l domains in batches of this many (0 = one batch)")
+	batchPause := fs.Duration("batch-pause", 30*time.Second, "with -batch-size, pause between batches; Ctrl-C during a run stops before the next batch")
+	ramp := fs.Duration("ramp", 0, "with -concurrent, spread the workers' first queries randomly over this window (e.g. 5s)")
+	memCacheSize := fs.Int("mem-cache-size", defaultMemCacheSize, "apex results kept in memory so repeated domains in a run skip crt.sh (0 = off)")
+	netConcurrency := fs.Int("net-concurrency", 0, "maximum simultaneous network requests across all workers (0 = unlimited)")
+	concurrent := fs.Bool("concurrent", false, "enable concurrent mode")
+	logFile := fs.String("log-file", "", "also write the run's log, uncolored and with full timestamps, to this file (even with -silent)")
+	logAppend := fs.Bool("log-append", false, "append to -log-file instead of rotating the previous log to <file>.1")
+	orderedOutput := fs.Bool("ordered-output", false, "with -concurrent, report domains and stream results in input order")
+	silent := fs.Bool("silent", false, "silent mode (only results)")
+	quietSummary := fs.Bool("quiet-summary", false, "print nothing but the final subdomain count (results still go to -o)")
+	showVersion := fs.Bool("version", false, "show version")
+	certHash := fs.String("sha256", "", "list all hostnames in the certificate with this SHA-256 fingerprint")
+	stdinJSON := fs.Bool("stdin-json", false, "read NDJSON {\"domain\": ...} requests from stdin and write NDJSON results to stdout until EOF")
+	matchPattern := fs.String("match-pattern", "", "raw crt.sh search with % and _ wildcards (e.g. %.dev.%.example.com) instead of -d")
+	withID := fs.Bool("with-id", false, "annotate each result with the crt.sh ID of a certificate that contains it")
+	withCounts := fs.Bool("with-counts", false, "annotate each result with the number of certificate entries naming it")
+	lowMemory := fs.Bool("low-memory", false, "dedupe with a bloom filter and stream list results (may drop rare false positives)")
+	bloomSize := fs.Int("bloom-size", 1000000, "expected number of unique subdomains for the -low-memory bloom filter")
+	skipInternal := fs.Bool("skip-internal", false, "drop hosts under internal or non-public suffixes (.local, .corp, ...)")
+	internalSuffixes := fs.String("internal-suffixes", strings.Join(defaultInternalSuffixes, ","), "comma-separated suffixes treated as internal by -skip-internal")
+	resolve := fs.Bool("resolve", false, "only keep subdomains that resolve in DNS")
+	doh := fs.String("doh", "", "resolve over DNS-over-HTTPS with this endpoint (e.g. https://cloudflare-dns.com/dns-query)")
+	onlyResolvableApex := fs.Bool("only-resolvable-apex", false, "in list mode, skip domains with no NS or A records before querying crt.sh (misses expired domains)")
+	ipRange := fs.String("ip-range", "", "with -resolve, keep only hosts with an address in these comma-separated CIDRs")
+	ipRangeExclude := fs.String("ip-range-exclude", "", "with -resolve, drop hosts with an address in these comma-separated CIDRs")
+	nsCandidates := fs.Bool("ns-candidates", false, "with -resolve, output \"apex @nameserver\" pairs for zone transfer checks instead of subdomains")
+	labelStatsCSV := fs.String("label-stats-csv", "", "also write every distinct leftmost label with its number of results to this CSV file")
+	freq := fs.Bool("freq", false, "output \"<certificates> <subdomain>\" lines, most certified first, instead of a plain list")
+	markdown := fs.Bool("markdown", false, "output a Markdown table (subdomain, resolved IPs, open ports) instead of a plain list; also used for -o files ending in .md")
+	hostsFormat := fs.Bool("hosts-format", false, "with -resolve, output hosts-file lines (IP<TAB>names) instead of a plain list")
+	head := fs.Bool("head", false, "report the first match as soon as it arrives, before the full response is downloaded")
+	scanPorts := fs.String("scan-ports", "", "with -resolve, try TCP connects to these comma-separated ports and report the open ones")
+	scanTimeout := fs.Duration("scan-timeout", defaultScanTimeout, "connect timeout for each -scan-ports probe")
+	withASN := fs.Bool("with-asn", false, "with -resolve, annotate results with the address, AS number and AS owner")
+	asnDBPath := fs.String("asn-db", "", "IP-to-ASN dataset for -with-asn (iptoasn.com TSV format)")
+	useSyslog := fs.Bool("syslog", false, "send discovered subdomains and the summary to syslog")
+	syslogAddr := fs.String("syslog-addr", "", "remote syslog server (e.g. udp://logs:514); default is the local daemon")
+	syslogFacility := fs.String("syslog-facility", "user", "syslog facility (user, daemon, local0-local7, ...)")
+	syslogSeverity := fs.String("syslog-severity", "info", "syslog severity (info, notice, warning, ...)")
+	firstSeen := fs.Bool("first-seen", false, "include the earliest certificate not_before per subdomain as first_seen in JSON output")
+	permute := fs.Bool("permute", false, "add permutations of discovered subdomains (combine with -resolve to keep only live ones)")
+	permuteWords := fs.String("permute-words", strings.Join(defaultPermuteWords, ","), "words for -permute: a file or comma-separated list")
+	webhook := fs.String("webhook", "", "POST newly discovered subdomains as JSON to this URL (Slack, Discord or generic)")
+	scopeFile := fs.String("scope", "", "file of allowed apex domains; queries for any other domain are refused")
+	watch := fs.Duration("watch", 0, "re-run the -d query at this interval until interrupted, printing only newly appeared subdomains with a timestamp")
+	headerComment := fs.Bool("header-comment", false, "start text output files with a \"# SubHunter <version> — <target> — <time> — <count> subdomains\" line")
+	subtractFile := fs.String("subtract", "", "file of subdomains already known from other sources (e.g. passive DNS) to remove from the results")
+	knownFile := fs.String("known", "", "file of already known subdomains; only others are sent to -webhook")
+	tlsMin := fs.String("tls-min", "", "lowest TLS version to offer: 1.0, 1.1, 1.2 or 1.3")
+	tlsMax := fs.String("tls-max", "", "highest TLS version to offer: 1.0, 1.1, 1.2 or 1.3")
+	tlsCiphers := fs.String("tls-ciphers", "", "comma-separated cipher suites to offer for TLS 1.2 and older, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256")
+	apiToken := fs.String("api-token", "", "API token for a crt.sh mirror with an authenticated tier, sent as a bearer token (or set "+apiTokenEnv+"); raises the default -c to 10")
+	basicAuth := fs.String("basic-auth", "", "user:pass for a crt.sh mirror behind HTTP basic auth (or set "+basicAuthEnv+")")
+	crtshURL := fs.String("crtsh-url", defaultEndpoint, "comma-separated crt.sh base URLs; each query fails over to the next before counting a retry")
+	cnOnly := fs.Bool("cn-only", false, "search and match certificate common names only, ignoring the other names (SANs) on each certificate")
+	tryAltQuery := fs.Bool("try-alt-query", false, "when a domain query returns nothing, query crt.sh once more for the bare domain without the wildcard prefix")
+	retryOnEmpty := fs.Bool("retry-on-empty", false, "retry a query that returns no certificates, taking it as empty only once the retries run out")
+	fallbackText := fs.Bool("fallback-text", false, "if the JSON API keeps returning HTML, parse crt.sh's regular results page instead")
+	timeline := fs.Bool("timeline", false, "show a histogram of certificate issuance per month")
+	seed := fs.Int64("seed", 0, "seed for randomized behavior (0 = time-based)")
+	retryMultiplier := fs.Float64("timeout-retry-multiplier", 1, "multiplier applied to the retry backoff delay")
+	retryBudget := fs.Int("retry-budget", 0, "maximum retries across the whole run, shared by all domains (0 = unlimited)")
+	maxResponseSize := fs.Int64("max-response-size", 512, "abort a crt.sh response larger than this many MB (0 = unlimited)")
+	cooldown := fs.Duration("cooldown", 10*time.Second, "after an HTTP 429, pause all workers for this long, or as long as Retry-After says (0 = off)")
+	backoffMax := fs.Duration("backoff-max", defaultBackoffMax, "upper bound on the computed delay between retries (0 for no bound)")
+	retryAfterMax := fs.Duration("retry-after-max", time.Minute, "upper bound on a server-sent Retry-After delay")
+	cpuProfile := fs.String("cpuprofile", "", "write a CPU profile (pprof) of the run to this file")
+	memProfile := fs.String("memprofile", "", "write a heap profile (pprof) at the end of the run to this file")
+	includeSANDomains := fs.Bool("include-san-domains", false, "with a single -d, also list hostnames under other domains found on the same certificates")
+	tldExpand := fs.String("tld-expand", "", "with -d set to a bare brand name, enumerate it under each of these TLDs (e.g. com,net,io)")
+	labelsOnly := fs.Bool("labels-only", false, "print only the labels left of the queried domain (api, dev.internal), e.g. to build wordlists")
+	flagSuspicious := fs.Bool("flag-suspicious", false, "mark results whose first label imitates the domain's brand (near-typo or lookalike characters) with [SUSPICIOUS]")
+	caStats := fs.Bool("ca-stats", false, "count the matching certificates per issuing CA and list the top CAs in the summary")
+	groupByCNAME := fs.Bool("group-by-cname", false, "after the results, resolve each subdomain's CNAME and show the subdomains grouped by canonical name")
+	wildcardTree := fs.Bool("wildcard-tree", false, "after the results, show each wildcard certificate name with the subdomains it covers")
+	unsorted := fs.Bool("unsorted", false, "skip the final sort; with a single -d, print each subdomain as soon as it arrives")
+	dedupScope := fs.String("dedup-scope", dedupGlobal, "how duplicates are removed across input domains: global, per-domain (once per input domain) or none (same as -no-dedupe)")
+	raw := fs.Bool("raw", false, "save the unmodified crt.sh JSON responses instead of extracting subdomains (NDJSON with the domain for several domains)")
+	noDedupe := fs.Bool("no-dedupe", false, "print every hostname occurrence in the order found, without removing duplicates or sorting (output can be large)")
+	manifest := fs.Bool("manifest", false, "write a JSON manifest of the run (version, flags, targets, counts) next to the first -o file")
+	minExpected := fs.Int("min-expected", 0, "exit non-zero if fewer subdomains than this are found, a heuristic for degraded crt.sh responses (0 = off)")
+	format := registerFormatFlags(fs)
+	destination := registerDestinationFlags(fs)
+
+	counts := countFlags(fs)
+	fs.Parse(args)
+
+	// Registered first so it runs after every other deferred cleanup.
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	if *showVersion {
+		fmt.Printf("SubHunter v%s\n", version)
+		os.Exit(0)
+	}
+
+	// stdout carries nothing but records in -stdin-json mode
+	*silent = *silent || *stdinJSON || *quietSummary
+
+	printBanner(*silent)
+	exitOnError(enumRules.check(fs, counts))
+
+	if *domain == "" && len(domainLists) == 0 && *certHash == "" && *matchPattern == "" && !*stdinJSON {
+		fmt.Printf("%s[ERR]%s Specify -d/--domain, -l/--list, -sha256, -match-pattern or -stdin-json\n\n", pink, reset)
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *certHash != "" {
+		hash, err := normalizeSHA256(*certHash)
+		if err != nil {
+			fmt.Printf("%s[ERR]%s %s\n\n", pink, reset, err)
+			os.Exit(1)
+		}
+		*certHash = hash
+	}
+
+	if *matchPattern != "" {
+		pattern, err := validateMatchPattern(*matchPattern)
+		exitOnError(err)
+		*matchPattern = pattern
+	}
+
+	// -d accepts a comma-separated list, which is handled like -l
+	var domains []string
+	for _, d := range strings.Split(*domain, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			domains = append(domains, d)
+		}
+	}
+	if *tldExpand != "" {
+		if len(domains) != 1 {
+			fmt.Printf("%s[ERR]%s -tld-expand needs a single -d name\n\n", pink, reset)
+			os.Exit(1)
+		}
+		expanded, err := expandTLDs(domains[0], *tldExpand)
+		exitOnError(err)
+		domains = expanded
+		*domain = strings.Join(domains, ",")
+	}
+
+	*seed = seedRandom(*seed)
+
+	if *apiToken == "" {
+		*apiToken = os.Getenv(apiTokenEnv)
+	}
+	if *apiToken != "" && *counts["c"] == 0 {
+		*concurrency = tokenConcurrency
+	}
+
+	hunter := NewSubHunter(*timeout, *concurrency, *silent)
+	hunter.apiToken = *apiToken
+	hunter.quietSummary = *quietSummary
+	tlsConfig, err := buildTLSConfig(*tlsMin, *tlsMax, *tlsCiphers)
+	exitOnError(err)
+	if tlsConfig != nil {
+		hunter.setTLSConfig(tlsConfig)
+	}
+	if *logFile != "" {
+		file, err := openLogFile(*logFile, *logAppend)
+		exitOnError(err)
+		defer file.Close()
+		hunter.logFile = &syncWriter{w: file}
+
+		var given []string
+		for name, value := range setFlags(fs) {
+			given = append(given, fmt.Sprintf("-%s=%s", name, value))
+		}
+		sort.Strings(given)
+		hunter.writeLogFile("info", fmt.Sprintf("SubHunter v%s started:", version), strings.Join(given, " "))
+	}
+	if err := validateDedupScope(*dedupScope); err != nil {
+		fmt.Printf("%s[ERR]%s %v\n\n", pink, reset, err)
+		os.Exit(1)
+	}
+	hunter.dedupScope = *dedupScope
+	hunter.noDedupe = *noDedupe || *dedupScope == dedupNone
+	hunter.unsorted = *unsorted
+	hunter.orderedOutput = *orderedOutput
+	hunter.wildcardTree = *wildcardTree
+	hunter.withID = *withID
+	hunter.timeline = *timeline
+	hunter.caStats = *caStats
+	hunter.flagSuspicious = *flagSuspicious
+	hunter.fallbackText = *fallbackText
+	hunter.retryOnEmpty = *retryOnEmpty
+	hunter.tryAltQuery = *tryAltQuery
+	hunter.cnOnly = *cnOnly
+	hunter.endpoints, err = parseEndpoints(*crtshURL)
+	exitOnError(err)
+	hunter.trackFirstSeen = *firstSeen
+	if *netConcurrency > 0 {
+		hunter.netSlots = make(chan struct{}, *netConcurrency)
+	}
+	hunter.retryMultiplier = *retryMultiplier
+	hunter.retryAfterMax = *retryAfterMax
+	hunter.backoffMax = *backoffMax
+	hunter.cooldown = *cooldown
+	hunter.maxResponseSize = *maxResponseSize << 20
+	hunter.retryBudget = newRetryBudget(*retryBudget)
+	hunter.ramp = *ramp
+	hunter.memCache = newResultCache(*memCacheSize)
+	if *includeSANDomains {
+		if len(domains) != 1 {
+			fmt.Printf("%s[ERR]%s -include-san-domains needs a single -d domain\n\n", pink, reset)
+			os.Exit(1)
+		}
+		hunter.includeSANDomains = true
+	}
+	if *scopeFile != "" {
+		scope, err := loadScope(*scopeFile)
+		exitOnError(err)
+		hunter.scope = scope
+		for _, d := range domains {
+			if !hunter.inScope(d) {
+				fmt.Printf("%s[ERR]%s %s is not covered by -scope %s\n\n", pink, reset, d, *scopeFile)
+				os.Exit(1)
+			}
+		}
+	}
+	if *doh != "" {
+		if !strings.HasPrefix(*doh, "https://") {
+			fmt.Printf("%s[ERR]%s -doh must be an https:// URL\n\n", pink, reset)
+			os.Exit(1)
+		}
+		hunter.dohURL = *doh
+	}
+
+	if *basicAuth == "" {
+		*basicAuth = os.Getenv(basicAuthEnv)
+	}
+	if *basicAuth != "" {
+		user, pass, err := parseBasicAuth(*basicAuth)
+		exitOnError(err)
+		hunter.basicAuthUser, hunter.basicAuthPass = user, pass
+	}
+	if hunter.basicAuthUser != "" && hunter.apiToken != "" {
+		fmt.Printf("%s[ERR]%s An API token and basic auth cannot be used together, both need the Authorization header\n\n", pink, reset)
+		os.Exit(1)
+	}
+	hunter.skipInternal = *skipInternal
+	if *subtractFile != "" {
+		subtract, err := hunter.loadSubtract(*subtractFile)
+		exitOnError(err)
+		hunter.subtract = subtract
+		hunter.subtracted = make(mapSet)
+	}
+	hunter.internalSuffixes = parseSuffixList(*internalSuffixes)
+	hunter.lowMemory = *lowMemory
+	hunter.bloomSize = *bloomSize
+
+	exitOnError(format.apply(hunter))
+	if *tldExpand != "" && *counts["sort"] == 0 {
+		// keep each expanded apex's subdomains together
+		hunter.sortMode = sortRegDomain
+	}
+	hunter.withCounts = *withCounts
+	hunter.freq = *freq
+	hunter.countCerts = *withCounts || hunter.sortMode == sortCount || *freq
+	outputs, err := destination.apply(hunter)
+	exitOnError(err)
+
+	hunter.resolve = *resolve
+	hunter.head = *head
+	hunter.onlyResolvableApex = *onlyResolvableApex
+
+	hunter.perDomainLimit = *perDomainLimit
+	if *batchSize > 0 {
+		hunter.batchSize = *batchSize
+		hunter.batchPause = *batchPause
+	}
+	if *batchSize > 0 || *watch > 0 {
+		// The first Ctrl-C lets the current batch finish and skips the
+		// rest, or ends -watch; with the handler removed, a second one
+		// quits immediately.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			stop()
+		}()
+		hunter.ctx = ctx
+	}
+
+	prof, err := startProfiling(*cpuProfile, *memProfile)
+	exitOnError(err)
+	defer prof.stop()
+	if *batchSize > 0 || *watch > 0 {
+		prof.stopOnInterrupt(1)
+	} else {
+		prof.stopOnInterrupt(0)
+	}
+
+	hunter.ipRanges, err = parseCIDRs(*ipRange)
+	exitOnError(err)
+	hunter.ipExcludes, err = parseCIDRs(*ipRangeExclude)
+	exitOnError(err)
+
+	hunter.hostsFormat = *hostsFormat
+	hunter.markdown = *markdown
+	hunter.nsCandidates = *nsCandidates
+
+	if *scanPorts != "" {
+		hunter.scanPorts, err = parsePorts(*scanPorts)
+		exitOnError(err)
+		hunter.scanTimeout = *scanTimeout
 	}
 
-	if *output != "" && len(subdomains) > 0 {
-		if err := hunter.saveToFile(subdomains, *output); err != nil {
+	if *withASN {
+		hunter.withASN = true
+		if db, err := loadASNDB(*asnDBPath); err != nil {
+			hunter.log("warn", "ASN data disabled", err.Error())
+		} else {
+			hunter.asnDB = db
+		}
+	}
+
+	if *permute {
+		hunter.permuteWords, err = loadPermuteWords(*permuteWords)
+		exitOnError(err)
+	}
+
+	if *useSyslog {
+		priority, err := syslogPriority(*syslogFacility, *syslogSeverity)
+		exitOnError(err)
+		if logger, err := openSyslog(*syslogAddr, priority); err != nil {
+			hunter.log("warn", "Syslog disabled", err.Error())
+		} else {
+			hunter.syslog = logger
+			defer logger.Close()
+		}
+	}
+
+	if len(outputs) > 1 && *raw {
+		fmt.Printf("%s[ERR]%s -raw writes to a single -o file\n\n", pink, reset)
+		os.Exit(1)
+	}
+	if len(outputs) > 1 && *lowMemory {
+		fmt.Printf("%s[ERR]%s -low-memory streams to a single -o file\n\n", pink, reset)
+		os.Exit(1)
+	}
+
+	var known map[string]bool
+	if *knownFile != "" {
+		known, err = loadKnown(*knownFile)
+		exitOnError(err)
+	}
+
+	if *watch > 0 && len(domains) != 1 {
+		fmt.Printf("%s[ERR]%s -watch follows a single -d domain\n\n", pink, reset)
+		os.Exit(1)
+	}
+
+	target := *domain
+	if target == "" {
+		target = domainLists.String()
+	}
+	if target == "" && *matchPattern != "" {
+		target = "pattern:" + *matchPattern
+	}
+	if target == "" {
+		target = "sha256:" + *certHash
+	}
+
+	if !*silent {
+		fmt.Printf("%s%s%s\n", pink, strings.Repeat("━", 60), reset)
+		fmt.Printf("%s%s[CONFIGURATION]%s\n", pink, bold, reset)
+		fmt.Printf("%s%s%s\n", pink, strings.Repeat("━", 60), reset)
+
+		outputStr := "stdout"
+		if len(outputs) > 0 {
+			outputStr = strings.Join(outputs, ", ")
+		}
+
+		fmt.Printf("  Target:       %s%s%s\n", pink, target, reset)
+		fmt.Printf("  Output:       %s%s%s\n", pink, outputStr, reset)
+		timeoutStr := fmt.Sprintf("%ds", *timeout)
+		if *timeout == 0 {
+			timeoutStr = "none"
+		}
+		fmt.Printf("  Timeout:      %s%s%s\n", pink, timeoutStr, reset)
+		fmt.Printf("  Seed:         %s%d%s\n", pink, *seed, reset)
+		if hunter.basicAuthUser != "" {
+			fmt.Printf("  Auth:         %s%s:****%s\n", pink, hunter.basicAuthUser, reset)
+		}
+		if hunter.apiToken != "" {
+			fmt.Printf("  Auth:         %sAPI token ****%s\n", pink, reset)
+		}
+
+		if (len(domainLists) > 0 || len(domains) > 1) && *concurrent {
+			fmt.Printf("  Workers:      %s%d%s\n", pink, *concurrency, reset)
+		}
+		if *netConcurrency > 0 {
+			fmt.Printf("  Net Limit:    %s%d%s\n", pink, *netConcurrency, reset)
+		}
+		if *batchSize > 0 {
+			fmt.Printf("  Batches:      %s%d domains, %s pause%s\n", pink, *batchSize, *batchPause, reset)
+		}
+
+		fmt.Printf("%s%s%s\n\n", pink, strings.Repeat("━", 60), reset)
+	}
+
+	if *headerComment {
+		hunter.headerTarget = target
+	}
+
+	if *stdinJSON {
+		hunter.processStdinJSON(os.Stdin)
+		return
+	}
+
+	if *watch > 0 {
+		hunter.watch(domains[0], *watch)
+		return
+	}
+
+	start := time.Now()
+	var subdomains []string
+
+	// Deferred before the -low-memory stream is, so it runs after the
+	// output file has been closed.
+	if *manifest {
+		defer func() {
+			targets := append(append([]string{}, domains...), domainLists...)
+			if len(targets) == 0 {
+				targets = []string{target}
+			}
+			path := outputs[0] + manifestSuffix
+			if err := writeManifest(path, hunter.buildManifest(targets, setFlags(fs), outputs, start)); err != nil {
+				hunter.log("error", "Failed to write manifest", err.Error())
+			} else {
+				hunter.log("info", "Manifest written to", path)
+			}
+		}()
+	}
+	if *raw {
+		hunter.rawLines = len(domainLists) > 0 || len(domains) > 1
+		hunter.rawOut = stdout
+		if len(outputs) > 0 {
+			out, err := hunter.openOutput(outputs[0])
+			if err != nil {
+				hunter.log("error", "Failed to create output file", err.Error())
+				os.Exit(1)
+			}
+			writer := bufio.NewWriter(out)
+			hunter.rawOut = writer
+			defer func() {
+				writer.Flush()
+				if err := out.Close(); err != nil {
+					hunter.log("error", "Failed to save output", err.Error())
+				}
+			}()
+		}
+	}
+	streamed := false // results were already written as they were found
+	// Single-domain results are printed as soon as they are found unless
+	// they still need post-processing or go out as one JSON document.
+	showLive := !hunter.jsonOutput && !*resolve && !*permute && !*labelsOnly && !*markdown && !*freq
+	hunter.streamLive = *unsorted && showLive && len(domains) == 1
+
+	if *matchPattern != "" {
+		hunter.log("info", "Target pattern", *matchPattern)
+		subdomains = hunter.processPattern(*matchPattern, showLive)
+	} else if *certHash != "" {
+		hunter.log("info", "Target certificate", *certHash)
+		subdomains = hunter.processCertificate(*certHash, showLive)
+	} else if len(domainLists) > 0 || len(domains) > 1 {
+		streamed = *lowMemory
+		if *lowMemory && len(outputs) > 0 {
+			out, err := hunter.openOutput(outputs[0])
+			if err != nil {
+				hunter.log("error", "Failed to create output file", err.Error())
+				os.Exit(1)
+			}
+			writer := bufio.NewWriter(out)
+			hunter.stream = writer
+			defer func() {
+				writer.Flush()
+				if err := out.Close(); err != nil {
+					hunter.log("error", "Failed to save output", err.Error())
+				}
+			}()
+		}
+		if len(domainLists) > 0 {
+			subdomains = hunter.processDomainsFromFiles(domainLists, *concurrent)
+		} else {
+			hunter.log("info", fmt.Sprintf("Target domains (%d)", len(domains)), *domain)
+			subdomains = hunter.processDomains(domains, *concurrent)
+		}
+	} else {
+		if !*silent {
+			hunter.log("info", "Target domain", *domain)
+		}
+		subdomains = hunter.processDomain(*domain, showLive)
+	}
+
+	if *permute && len(subdomains) > 0 {
+		subdomains = hunter.addPermutations(subdomains)
+		hunter.totalFound = len(subdomains)
+	}
+
+	if *resolve && len(subdomains) > 0 {
+		subdomains = hunter.resolveAll(subdomains)
+		hunter.totalFound = len(subdomains)
+	}
+
+	if len(hunter.scanPorts) > 0 && len(subdomains) > 0 {
+		hunter.scanAll(subdomains)
+	}
+
+	if hunter.nsCandidates && len(subdomains) > 0 {
+		hunter.collectNameservers(subdomains)
+	}
+
+	results := subdomains
+	if *labelsOnly {
+		results = hunter.relativeLabels(subdomains)
+	}
+
+	if len(outputs) > 0 && len(results) > 0 {
+		if err := hunter.saveToFiles(results, outputs); err != nil {
 			hunter.log("error", "Failed to save file", err.Error())
 		}
+	} else if len(outputs) == 0 && !streamed {
+		if hunter.jsonOutput || hunter.hostsFormat || hunter.nsCandidates || hunter.markdown || hunter.freq {
+			hunter.writeResults(stdout, results)
+		} else if !showLive && (*certHash != "" || *matchPattern != "" || len(domains) == 1 || *labelsOnly) {
+			for _, sub := range results {
+				hunter.printResult(sub)
+			}
+		}
+	}
+
+	if len(hunter.sanDomains) > 0 {
+		hunter.printSANDomains()
+	}
+
+	if *labelStatsCSV != "" {
+		if err := hunter.writeLabelStats(subdomains, *labelStatsCSV); err != nil {
+			hunter.log("error", "Failed to save label statistics", err.Error())
+		}
+	}
+
+	if *timeline {
+		hunter.printTimeline()
+	}
+
+	if *wildcardTree {
+		hunter.printWildcardTree(subdomains)
+	}
+
+	if *groupByCNAME {
+		hunter.printCNAMEGroups(subdomains)
+	}
+
+	if *webhook != "" {
+		if fresh := newSubdomains(subdomains, known); len(fresh) == 0 {
+			hunter.log("info", "No new subdomains, webhook not called", "")
+		} else if err := hunter.notifyWebhook(*webhook, target, fresh); err != nil {
+			hunter.log("error", "Failed to notify webhook", err.Error())
+		}
+	}
+
+	hunter.logEndpointHits()
+	if hunter.subtract != nil {
+		hunter.log("info", fmt.Sprintf("Subtracted %d subdomains listed in", len(hunter.subtracted)), *subtractFile)
 	}
 
 	elapsed := time.Since(start)
-	hunter.printSummary(elapsed)
+	hunter.syslogResults(subdomains)
+	hunter.syslogSummary(elapsed)
+	hunter.printSummary(subdomains, elapsed)
+	hunter.writeLogFile("info", fmt.Sprintf("Finished in %.2fs with", elapsed.Seconds()), fmt.Sprintf("%d subdomains", hunter.totalFound))
+
+	if *minExpected > 0 && hunter.totalFound < *minExpected {
+		hunter.log("warn", fmt.Sprintf("Found %d subdomains, fewer than -min-expected", hunter.totalFound), strconv.Itoa(*minExpected))
+		exitCode = 1
+	}
 }
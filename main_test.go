@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// writerFunc adapts a function to io.Writer.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+// testHunter returns a quiet SubHunter that queries the crt.sh stand-in at
+// url and retries without waiting.
+func testHunter(url string) *SubHunter {
+	s := NewSubHunter(defaultTimeout, 1, true)
+	s.endpoints = []string{url}
+	s.backoffMax = time.Millisecond
+	return s
+}
+
+// largeResponse returns n certificate entries for example.com with a few
+// names each, about a quarter of them repeats.
+func largeResponse(n int) []CRTResponse {
+	results := make([]CRTResponse, n)
+	for i := range results {
+		results[i] = CRTResponse{
+			ID:        int64(i + 1),
+			NameValue: fmt.Sprintf("host%d.example.com\n*.host%d.example.com\nwww.example.com\nother%d.example.net", i, i*3/4, i),
+		}
+	}
+	return results
+}
+
+func BenchmarkExtractSubdomains(b *testing.B) {
+	results := largeResponse(50000)
+	for _, workers := range []int{1, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			s := NewSubHunter(defaultTimeout, workers, true)
+			s.domainPattern("example.com")
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.extractSubdomains("example.com", results)
+			}
+		})
+	}
+}
+
+func TestSubdomainProblem(t *testing.T) {
+	tests := []struct {
+		name    string
+		problem string // "" for a valid name
+	}{
+		{"example.com", ""},
+		{"api.example.com", ""},
+		{"API.Example.COM", ""},
+		{"*.example.com", ""},
+		{"xn--bcher-kva.example.com", ""},
+		{"my-host1.example.com", ""},
+		{"_dmarc.example.com", ""},
+		{"_sip._tcp.example.com", ""},
+		{"", "empty"},
+		{"a..example.com", "empty label"},
+		{"a.example.com.", "empty label"},
+		{strings.Repeat("a", 64) + ".example.com", "label too long"},
+		{strings.Repeat("a.", 127) + "com", "too long"},
+		{"a b.example.com", "invalid character ' '"},
+		{"a.example.com # crt.sh/?id=5", "invalid character ' '"},
+		{"| a.example.com |", "invalid character '|'"},
+		{"a.example.com:443", "invalid character ':'"},
+		{"https://a.example.com", "invalid character ':'"},
+		{"a/b.example.com", "invalid character '/'"},
+		{"*.*.example.com", "invalid character '*'"},
+		{"a*.example.com", "invalid character '*'"},
+		{"bücher.example.com", "invalid character 'ü'"},
+		{"-a.example.com", "label starts or ends with a hyphen"},
+		{"a-.example.com", "label starts or ends with a hyphen"},
+	}
+	for _, tt := range tests {
+		if problem := subdomainProblem(tt.name); problem != tt.problem {
+			t.Errorf("subdomainProblem(%q) = %q, want %q", tt.name, problem, tt.problem)
+		}
+	}
+}
+
+func TestNormalizeListEntry(t *testing.T) {
+	tests := []struct {
+		line, want string
+	}{
+		{"www.example.com", "www.example.com"},
+		{"  WWW.Example.com  ", "www.example.com"},
+		{"*.example.com", "example.com"},
+		{"bücher.example.com", "xn--bcher-kva.example.com"},
+		{"BÜCHER.example.com", "xn--bcher-kva.example.com"},
+		{"www.example.com # crt.sh/?id=5", "www.example.com"},
+		{"www.example.com (3)", "www.example.com"},
+		{"www.example.com [SUSPICIOUS]", "www.example.com"},
+		{"www.example.com 192.0.2.1 AS64496 Example Org", "www.example.com"},
+		{"3 www.example.com", "www.example.com"},
+		{"https://www.example.com:8443", "www.example.com"},
+		{"www.example.com:22", "www.example.com"},
+		{"# SubHunter v1.0.1 — example.com — 2026-01-01T00:00:00Z — 2 subdomains", ""},
+		// not SubHunter output: left whole for validation to reject
+		{"bad host.example.com", "bad host.example.com"},
+	}
+	for _, tt := range tests {
+		if got := normalizeListEntry(tt.line); got != tt.want {
+			t.Errorf("normalizeListEntry(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestHeadReportsFirstResultWhileDownloading(t *testing.T) {
+	reported := make(chan struct{})
+	var once atomic.Bool
+	orig := stdout
+	stdout = writerFunc(func(p []byte) (int, error) {
+		if strings.Contains(string(p), "First result") && once.CompareAndSwap(false, true) {
+			close(reported)
+		}
+		return len(p), nil
+	})
+	t.Cleanup(func() { stdout = orig })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id":1,"name_value":"first.example.com"},`)
+		w.(http.Flusher).Flush()
+		select {
+		case <-reported:
+		case <-time.After(5 * time.Second):
+			t.Error("first result was not reported before the response finished")
+		}
+		fmt.Fprint(w, `{"id":2,"name_value":"second.example.com"}]`)
+	}))
+	defer server.Close()
+
+	s := testHunter(server.URL)
+	s.silent = false
+	s.head = true
+	subdomains, err := s.queryAPI("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subdomains) != 2 {
+		t.Errorf("got %q, want both subdomains", subdomains)
+	}
+}
+
+func TestFetchRetriesAfterTimeout(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			// stall the first request past the client timeout
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		fmt.Fprint(w, `[{"id":1,"name_value":"www.example.com"}]`)
+	}))
+	defer server.Close()
+
+	s := testHunter(server.URL)
+	s.client.Timeout = 100 * time.Millisecond
+	start := time.Now()
+	subdomains, err := s.queryAPI("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("query took %s, the timeout did not abort the stalled request", elapsed)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("server saw %d requests, want 2", n)
+	}
+	if len(subdomains) != 1 || subdomains[0] != "www.example.com" {
+		t.Errorf("got %q, want [www.example.com]", subdomains)
+	}
+}
+
+func BenchmarkDomainPattern(b *testing.B) {
+	b.Run("compile", func(b *testing.B) {
+		s := NewSubHunter(defaultTimeout, 1, true)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.patterns = nil
+			s.domainPattern("example.com")
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		s := NewSubHunter(defaultTimeout, 1, true)
+		s.domainPattern("example.com")
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			s.domainPattern("example.com")
+		}
+	})
+}
+
+func TestDomainMatches(t *testing.T) {
+	s := NewSubHunter(defaultTimeout, 1, true)
+	pattern := s.domainPattern("example.com")
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"example.com", []string{"example.com"}},
+		{"www.example.com", []string{"www.example.com"}},
+		{"a.b.example.com", []string{"a.b.example.com"}},
+		{"*.example.com", []string{"example.com"}},
+		{"notexample.com", nil},
+		{"evil-example.com", nil},
+		{"example.com.attacker.net", nil},
+		{"www.example.com.attacker.net", nil},
+		{"example.com-attacker.net", nil},
+		{"example.community", nil},
+		{"WWW.Example.COM", []string{"www.example.com"}},
+		{"www.example.com.", []string{"www.example.com"}},
+		{"example.com.", []string{"example.com"}},
+		{"a.example.com, b.example.com", []string{"a.example.com", "b.example.com"}},
+	}
+	for _, tt := range tests {
+		if got := domainMatches(pattern, tt.text, "example.com"); !slices.Equal(got, tt.want) {
+			t.Errorf("domainMatches(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTrimQueryPrefix(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"*.example.com", "example.com"},
+		{"%.example.com", "example.com"},
+		{"%25.example.com", "example.com"},
+		{"%2A.example.com", "example.com"},
+		{"%2a.example.com", "example.com"},
+		{".example.com", "example.com"},
+		{"..example.com", "example.com"},
+		{"%.%.example.com", "example.com"},
+		{"%.Example.COM", "Example.COM"},
+		{"*.API.Example.com", "API.Example.com"},
+		{"example.com", "example.com"},
+		{"Example.COM", "Example.COM"},
+		{"25.example.com", "25.example.com"},
+		{"dev.%.example.com", "dev.%.example.com"},
+		{"a-b.example.com", "a-b.example.com"},
+		{"%", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := trimQueryPrefix(tt.input); got != tt.want {
+			t.Errorf("trimQueryPrefix(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
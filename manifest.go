@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// manifestSuffix is appended to the first -o name to get the -manifest path.
+const manifestSuffix = ".manifest.json"
+
+// redactedFlags hold credentials or secret URLs and are not written to the
+// manifest as given.
+var redactedFlags = map[string]bool{
+	"api-token":  true,
+	"basic-auth": true,
+	"webhook":    true,
+	"output-url": true,
+}
+
+// runManifest describes how a result set was produced.
+type runManifest struct {
+	Version        string            `json:"version"`
+	StartedAt      time.Time         `json:"started_at"`
+	FinishedAt     time.Time         `json:"finished_at"`
+	ElapsedSeconds float64           `json:"elapsed_seconds"`
+	Targets        []string          `json:"targets"`
+	Flags          map[string]string `json:"flags"`
+	Sources        []string          `json:"sources"`
+	Outputs        []string          `json:"outputs"`
+	DomainCounts   map[string]int    `json:"domain_counts,omitempty"`
+	Total          int               `json:"total"`
+}
+
+// setFlags returns the flags given on the command line with their values.
+func setFlags(fs *flag.FlagSet) map[string]string {
+	flags := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		if redactedFlags[f.Name] {
+			flags[f.Name] = "****"
+			return
+		}
+		flags[f.Name] = f.Value.String()
+	})
+	return flags
+}
+
+// noteSource records a crt.sh endpoint queried during the run.
+func (s *SubHunter) noteSource(endpoint string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sources == nil {
+		s.sources = make(map[string]bool)
+	}
+	s.sources[endpoint] = true
+}
+
+// recordDomainCount keeps the number of subdomains found for a queried
+// domain, for the manifest.
+func (s *SubHunter) recordDomainCount(domain string, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.domainCounts[domain] = count
+}
+
+// buildManifest assembles the manifest of a finished run.
+func (s *SubHunter) buildManifest(targets []string, flags map[string]string, outputs []string, started time.Time) runManifest {
+	finished := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sources := make([]string, 0, len(s.sources))
+	for endpoint := range s.sources {
+		sources = append(sources, endpoint)
+	}
+	sort.Strings(sources)
+
+	return runManifest{
+		Version:        version,
+		StartedAt:      started.UTC(),
+		FinishedAt:     finished.UTC(),
+		ElapsedSeconds: finished.Sub(started).Seconds(),
+		Targets:        targets,
+		Flags:          flags,
+		Sources:        sources,
+		Outputs:        outputs,
+		DomainCounts:   s.domainCounts,
+		Total:          s.totalFound,
+	}
+}
+
+// writeManifest writes the manifest to path atomically: it is written to a
+// temporary file in the same directory and renamed into place, so a reader
+// never sees a partial manifest.
+func writeManifest(path string, manifest runManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
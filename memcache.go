@@ -0,0 +1,91 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+)
+
+// defaultMemCacheSize is the default number of apex results kept in memory.
+const defaultMemCacheSize = 128
+
+// resultCache is a thread-safe LRU of apex -> subdomains for the current
+// run, so an apex requested again is answered without another crt.sh query.
+// Hits and misses are counted for the summary.
+type resultCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // front is most recently used
+	entries map[string]*list.Element
+	hits    int
+	misses  int
+}
+
+type resultCacheEntry struct {
+	apex       string
+	subdomains []string
+}
+
+// newResultCache returns a cache holding up to size apexes, or nil (no
+// caching) when size is 0.
+func newResultCache(size int) *resultCache {
+	if size <= 0 {
+		return nil
+	}
+	return &resultCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns a copy of the cached subdomains of apex, so callers may sort
+// or filter them in place.
+func (c *resultCache) get(apex string) ([]string, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[apex]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(elem)
+	return append([]string(nil), elem.Value.(*resultCacheEntry).subdomains...), true
+}
+
+// put stores the subdomains of apex, evicting the least recently used apex
+// when the cache is full.
+func (c *resultCache) put(apex string, subdomains []string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	subdomains = append([]string(nil), subdomains...)
+	if elem, ok := c.entries[apex]; ok {
+		elem.Value.(*resultCacheEntry).subdomains = subdomains
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[apex] = c.order.PushFront(&resultCacheEntry{apex: apex, subdomains: subdomains})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*resultCacheEntry).apex)
+	}
+}
+
+// stats returns the hit and miss counts so far.
+func (c *resultCache) stats() (hits, misses int) {
+	if c == nil {
+		return 0, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
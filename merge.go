@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/idna"
+)
+
+// lockOutput takes the lock guarding an output file, waiting up to
+// s.lockTimeout for other SubHunter processes to release it.
+func (s *SubHunter) lockOutput(filename string) (func(), error) {
+	lockPath := filename + ".lock"
+	deadline := time.Now().Add(s.lockTimeout)
+
+	for {
+		unlock, ok, err := tryLockFile(lockPath)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return unlock, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out after %s waiting for lock %s", s.lockTimeout, lockPath)
+		}
+		if !s.wait(100 * time.Millisecond) {
+			return nil, fmt.Errorf("%w waiting for lock %s", ErrInterrupted, lockPath)
+		}
+	}
+}
+
+// readExistingResults loads the subdomains already present in an output
+// file, in the format its name implies. A missing file yields no results.
+func (s *SubHunter) readExistingResults(filename string) ([]string, error) {
+	switch s.formatFor(filename) {
+	case formatCSV:
+		file, err := os.Open(filename)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		records, err := csv.NewReader(file).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("cannot merge into %s: %v", filename, err)
+		}
+		var subdomains []string
+		for i, record := range records {
+			if i == 0 && record[0] == csvHeader[0] {
+				continue
+			}
+			subdomains = append(subdomains, record[0])
+		}
+		return subdomains, nil
+	case formatJSON:
+		data, err := os.ReadFile(filename)
+		if errors.Is(err, os.ErrNotExist) || (err == nil && len(data) == 0) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var results []Result
+		if err := json.Unmarshal(data, &results); err != nil {
+			return nil, fmt.Errorf("cannot merge into %s: %v", filename, err)
+		}
+		subdomains := make([]string, len(results))
+		for i, result := range results {
+			subdomains[i] = result.Subdomain
+		}
+		return subdomains, nil
+	}
+
+	lines, err := readLines(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var subdomains []string
+	for _, line := range lines {
+		var name string
+		if s.formatFor(filename) == formatMarkdown {
+			name = markdownHostname(line)
+		} else {
+			name = existingHostname(line)
+		}
+		if name != "" {
+			subdomains = append(subdomains, name)
+		}
+	}
+	return subdomains, nil
+}
+
+// existingHostname extracts the subdomain from a line of a text output
+// file, undoing the annotations a result line can carry: the scheme and
+// port of -urls or -scan-ports, the leading count of -freq, and the
+// annotations that follow the name (ASN data, markers, counts, crt.sh IDs).
+// Comment lines yield "". A line that is not SubHunter output is returned
+// as is, for validation to reject.
+func existingHostname(line string) string {
+	if strings.HasPrefix(line, "#") {
+		return ""
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	name, rest := fields[0], fields[1:]
+	if len(fields) == 2 && strings.Trim(name, "0123456789") == "" {
+		name, rest = fields[1], nil
+	}
+	if len(rest) > 0 && !isAnnotation(rest[0]) {
+		return line
+	}
+	if _, after, ok := strings.Cut(name, "://"); ok {
+		name, _, _ = strings.Cut(after, "/")
+	}
+	if host, _, err := net.SplitHostPort(name); err == nil {
+		name = host
+	}
+	// -output-encoding unicode names are read back in the punycode form
+	// results are kept in
+	if ascii, err := idna.ToASCII(strings.ToLower(name)); err == nil {
+		name = ascii
+	}
+	return name
+}
+
+// isAnnotation reports whether field can start the annotations formatResult
+// appends to a name.
+func isAnnotation(field string) bool {
+	return strings.HasPrefix(field, "#") || strings.HasPrefix(field, "(") ||
+		strings.HasPrefix(field, "[") || net.ParseIP(field) != nil
+}
+
+// markdownHostname returns the subdomain in a row of a -markdown table,
+// or "" for the header, the separator and anything that is not a row.
+func markdownHostname(line string) string {
+	cells := strings.Split(strings.Trim(line, "|"), "|")
+	if !strings.HasPrefix(line, "|") || len(cells) == 0 {
+		return ""
+	}
+	name := strings.TrimSpace(cells[0])
+	if name == "Subdomain" || strings.Trim(name, "-") == "" {
+		return ""
+	}
+	return existingHostname(name)
+}
+
+// mergeToFile unions subdomains with the existing contents of filename under
+// a file lock, so concurrent processes sharing one output don't clobber
+// each other.
+func (s *SubHunter) mergeToFile(subdomains []string, filename string) error {
+	unlock, err := s.lockOutput(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	existing, err := s.readExistingResults(filename)
+	if err != nil {
+		return err
+	}
+
+	merged, _ := s.dedupeSubdomains(append(existing, subdomains...))
+	s.log("info", fmt.Sprintf("Merging %d new results into %d existing", len(subdomains), len(existing)), filename)
+
+	return s.writeFile(merged, filename)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMergeAnnotatedOutput(t *testing.T) {
+	first := []string{"a.example.com", "b.example.com", "xn--bcher-kva.example.com"}
+	second := []string{"b.example.com", "c.example.com"}
+	want := []string{"a.example.com", "b.example.com", "c.example.com", "xn--bcher-kva.example.com"}
+
+	tests := []struct {
+		name  string
+		file  string
+		setup func(s *SubHunter)
+	}{
+		{"plain", "subs.txt", func(s *SubHunter) {}},
+		{"urls", "subs.txt", func(s *SubHunter) {
+			s.urls = true
+			s.bothSchemes = true
+			s.ports = []int{443, 8443}
+		}},
+		{"with-id", "subs.txt", func(s *SubHunter) {
+			s.withID = true
+			for i, sub := range want {
+				s.recordCertID(sub, int64(i+5))
+			}
+		}},
+		{"with-counts", "subs.txt", func(s *SubHunter) {
+			s.withCounts = true
+			s.recordCertCount("a.example.com")
+		}},
+		{"with-asn", "subs.txt", func(s *SubHunter) {
+			s.withASN = true
+			for _, sub := range want {
+				s.ips[sub] = []net.IP{net.ParseIP("192.0.2.1")}
+			}
+		}},
+		{"freq", "subs.txt", func(s *SubHunter) {
+			s.freq = true
+			s.recordCertCount("b.example.com")
+		}},
+		{"scan-ports", "subs.txt", func(s *SubHunter) {
+			s.scanPorts = []int{22, 443}
+			for _, sub := range want {
+				s.openPorts[sub] = []int{443}
+			}
+		}},
+		{"unicode", "subs.txt", func(s *SubHunter) {
+			s.outputEncoding = encodingUnicode
+		}},
+		{"markdown", "subs.md", func(s *SubHunter) {
+			s.ips["a.example.com"] = []net.IP{net.ParseIP("192.0.2.1")}
+		}},
+		{"csv", "subs.csv", func(s *SubHunter) {}},
+		{"json", "subs.json", func(s *SubHunter) {}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), tt.file)
+			s := NewSubHunter(defaultTimeout, 1, true)
+			s.merge = true
+			tt.setup(s)
+
+			// merging the same batch again must leave the file as it was
+			var sizes []int
+			for _, batch := range [][]string{first, second, second} {
+				if err := s.saveToFile(batch, filename); err != nil {
+					t.Fatal(err)
+				}
+				existing, err := s.readExistingResults(filename)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sizes = append(sizes, len(existing))
+			}
+			if sizes[2] != sizes[1] {
+				t.Errorf("remerging grew the file from %d to %d results", sizes[1], sizes[2])
+			}
+
+			existing, err := s.readExistingResults(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := s.dedupeSubdomains(existing); !slices.Equal(got, want) {
+				t.Errorf("merged results = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestMergeHeaderComment(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "subs.txt")
+	s := NewSubHunter(defaultTimeout, 1, true)
+	s.merge = true
+	s.headerTarget = "example.com"
+
+	for _, batch := range [][]string{{"a.example.com"}, {"b.example.com"}, {"a.example.com"}} {
+		if err := s.saveToFile(batch, filename); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("file has %d lines, want a header and 2 results:\n%s", len(lines), data)
+	}
+	if !strings.HasPrefix(lines[0], "# SubHunter v") || !strings.HasSuffix(lines[0], "— 2 subdomains") {
+		t.Errorf("header = %q, want a fresh header counting 2 subdomains", lines[0])
+	}
+	if want := []string{"a.example.com", "b.example.com"}; !slices.Equal(lines[1:], want) {
+		t.Errorf("results = %q, want %q", lines[1:], want)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// resultApexes returns the distinct registrable domains of the subdomains,
+// in order of first appearance.
+func resultApexes(subdomains []string) []string {
+	seen := make(mapSet)
+	var apexes []string
+	for _, sub := range subdomains {
+		if apex := registrableDomain(sub); seen.add(apex) {
+			apexes = append(apexes, apex)
+		}
+	}
+	return apexes
+}
+
+// collectNameservers looks up the name servers of every apex among the
+// subdomains with the worker pool, for -ns-candidates.
+func (s *SubHunter) collectNameservers(subdomains []string) {
+	apexes := resultApexes(subdomains)
+	s.log("run", fmt.Sprintf("Looking up name servers of %d apex domains", len(apexes)), "")
+
+	found := make([][]string, len(apexes))
+	semaphore := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+
+	for i, apex := range apexes {
+		wg.Add(1)
+		go func(idx int, domain string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			hosts, err := s.lookupNS(domain)
+			if err != nil {
+				s.log("warn", "No name servers found for", domain)
+				return
+			}
+			found[idx] = hosts
+		}(i, apex)
+	}
+	wg.Wait()
+
+	s.nameservers = make(map[string][]string, len(apexes))
+	for i, apex := range apexes {
+		hosts := dedupeStrings(found[i])
+		sort.Strings(hosts)
+		s.nameservers[apex] = hosts
+	}
+}
+
+// dedupeStrings returns values without repeats, keeping the first
+// occurrence of each.
+func dedupeStrings(values []string) []string {
+	seen := make(mapSet)
+	var unique []string
+	for _, v := range values {
+		if seen.add(v) {
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
+// writeNSCandidates writes one "apex @nameserver" line per name server of
+// each apex, ready for "dig axfr".
+func (s *SubHunter) writeNSCandidates(w io.Writer, subdomains []string) {
+	var lines []string
+	for _, apex := range resultApexes(subdomains) {
+		for _, ns := range s.nameservers[apex] {
+			lines = append(lines, s.encodeName(apex)+" @"+ns)
+		}
+	}
+	s.writeLines(w, lines)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Sort modes accepted by -sort.
+const (
+	sortAlpha        = "alpha"
+	sortRegDomain    = "regdomain"
+	sortReverseLabel = "reverse-label"
+	sortCount        = "count"
+)
+
+func validateSortMode(mode string) error {
+	switch mode {
+	case sortAlpha, sortRegDomain, sortReverseLabel, sortCount:
+		return nil
+	}
+	return fmt.Errorf("unknown sort mode %q (expected %s, %s, %s or %s)", mode, sortAlpha, sortRegDomain, sortReverseLabel, sortCount)
+}
+
+// sortResults orders subdomains in place according to the configured mode.
+func (s *SubHunter) sortResults(subdomains []string) {
+	if s.noDedupe || s.unsorted {
+		return // keep the order the names were found in
+	}
+	switch s.sortMode {
+	case sortRegDomain:
+		keys := make(map[string]string, len(subdomains))
+		for _, sub := range subdomains {
+			keys[sub] = registrableDomain(sub)
+		}
+		sort.Slice(subdomains, func(i, j int) bool {
+			a, b := subdomains[i], subdomains[j]
+			if keys[a] != keys[b] {
+				return keys[a] < keys[b]
+			}
+			return a < b
+		})
+	case sortReverseLabel:
+		keys := make(map[string][]string, len(subdomains))
+		for _, sub := range subdomains {
+			keys[sub] = reversedLabels(sub)
+		}
+		sort.Slice(subdomains, func(i, j int) bool {
+			return compareLabels(keys[subdomains[i]], keys[subdomains[j]]) < 0
+		})
+	case sortCount:
+		counts := make(map[string]int, len(subdomains))
+		for _, sub := range subdomains {
+			counts[sub] = s.certCount(sub)
+		}
+		sort.Slice(subdomains, func(i, j int) bool {
+			a, b := subdomains[i], subdomains[j]
+			if counts[a] != counts[b] {
+				return counts[a] > counts[b]
+			}
+			return a < b
+		})
+	default:
+		sort.Strings(subdomains)
+	}
+}
+
+// reversedLabels splits a hostname into its labels, top-level first, so that
+// "api.example.com" becomes [com example api].
+func reversedLabels(name string) []string {
+	labels := strings.Split(name, ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return labels
+}
+
+// compareLabels orders label lists label by label, a parent before its
+// children.
+func compareLabels(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := strings.Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
+
+// registrableDomain returns the eTLD+1 of name according to the public
+// suffix list, or name itself if it has none.
+func registrableDomain(name string) string {
+	apex, err := publicsuffix.EffectiveTLDPlusOne(name)
+	if err != nil {
+		return name
+	}
+	return apex
+}
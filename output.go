@@ -0,0 +1,486 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Result is the structured form of a discovered subdomain used by the JSON
+// output modes.
+type Result struct {
+	Subdomain  string   `json:"subdomain"`
+	CertID     int64    `json:"cert_id,omitempty"`
+	CertCount  int      `json:"cert_count,omitempty"`
+	IPs        []string `json:"ips,omitempty"`
+	FirstSeen  string   `json:"first_seen,omitempty"`
+	ASN        uint32   `json:"asn,omitempty"`
+	ASOrg      string   `json:"as_org,omitempty"`
+	OpenPorts  []int    `json:"open_ports,omitempty"`
+	Suspicious bool     `json:"suspicious,omitempty"`
+
+	Meta map[string]json.RawMessage `json:"meta,omitempty"` // fields of an NDJSON -l entry
+}
+
+func (s *SubHunter) buildResult(subdomain string) Result {
+	result := Result{Subdomain: s.encodeName(subdomain)}
+	if s.withID {
+		result.CertID = s.certID(subdomain)
+	}
+	if s.withCounts {
+		result.CertCount = s.certCount(subdomain)
+	}
+	if s.trackFirstSeen {
+		s.mu.Lock()
+		if seen, ok := s.firstSeen[subdomain]; ok {
+			result.FirstSeen = seen.Format("2006-01-02")
+		}
+		s.mu.Unlock()
+	}
+	for _, ip := range s.resolvedIPs(subdomain) {
+		result.IPs = append(result.IPs, ip.String())
+	}
+	if s.withASN {
+		if _, entry, ok := s.asnInfo(subdomain); ok {
+			result.ASN = entry.asn
+			result.ASOrg = entry.org
+		}
+	}
+	if len(s.scanPorts) > 0 {
+		result.OpenPorts = s.hostOpenPorts(subdomain)
+	}
+	if s.flagSuspicious {
+		result.Suspicious = isSuspicious(subdomain)
+	}
+	result.Meta = s.metaFor(subdomain)
+	return result
+}
+
+// resultFields are the JSON fields of a Result that -fields can select.
+var resultFields = []string{"subdomain", "cert_id", "cert_count", "ips", "first_seen", "asn", "as_org", "open_ports", "suspicious", "meta"}
+
+// parseFields reads a -fields list, rejecting names that are not fields of
+// a Result.
+func parseFields(list string) (map[string]bool, error) {
+	fields := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(resultFields, name) {
+			return nil, fmt.Errorf("unknown field %q (expected %s)", name, strings.Join(resultFields, ", "))
+		}
+		fields[name] = true
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("-fields lists no fields")
+	}
+	return fields, nil
+}
+
+// jsonResult builds the JSON form of a result, keeping only the -fields
+// selection if one is set. The subdomain identifies the result and is
+// always kept.
+func (s *SubHunter) jsonResult(subdomain string) Result {
+	result := s.buildResult(subdomain)
+	if s.fields == nil {
+		return result
+	}
+	if !s.fields["cert_id"] {
+		result.CertID = 0
+	}
+	if !s.fields["cert_count"] {
+		result.CertCount = 0
+	}
+	if !s.fields["ips"] {
+		result.IPs = nil
+	}
+	if !s.fields["first_seen"] {
+		result.FirstSeen = ""
+	}
+	if !s.fields["asn"] {
+		result.ASN = 0
+	}
+	if !s.fields["as_org"] {
+		result.ASOrg = ""
+	}
+	if !s.fields["open_ports"] {
+		result.OpenPorts = nil
+	}
+	if !s.fields["suspicious"] {
+		result.Suspicious = false
+	}
+	if !s.fields["meta"] {
+		result.Meta = nil
+	}
+	return result
+}
+
+// Result file formats. Output files pick theirs from the extension.
+const (
+	formatText     = "text"
+	formatJSON     = "json"
+	formatCSV      = "csv"
+	formatMarkdown = "markdown"
+)
+
+// formatFor returns the format to use for an output destination: the one
+// named by its extension (ignoring a trailing ".gz"), or the -json setting
+// for anything else.
+func (s *SubHunter) formatFor(dest string) string {
+	dest = strings.TrimSuffix(strings.ToLower(dest), ".gz")
+	switch path.Ext(dest) {
+	case ".json":
+		return formatJSON
+	case ".csv":
+		return formatCSV
+	case ".md":
+		return formatMarkdown
+	case ".txt":
+		return formatText
+	}
+	if s.jsonOutput {
+		return formatJSON
+	}
+	if s.markdown {
+		return formatMarkdown
+	}
+	return formatText
+}
+
+// writeResults writes subdomains to w in the configured output format.
+func (s *SubHunter) writeResults(w io.Writer, subdomains []string) error {
+	return s.writeResultsAs(w, subdomains, s.formatFor(""))
+}
+
+// writeResultsAs writes subdomains to w in the given format.
+func (s *SubHunter) writeResultsAs(w io.Writer, subdomains []string, format string) error {
+	writer := bufio.NewWriter(w)
+
+	switch {
+	case format == formatCSV:
+		if err := s.writeCSV(writer, subdomains); err != nil {
+			return err
+		}
+	case format == formatMarkdown:
+		s.writeMarkdown(writer, subdomains)
+	case format == formatJSON:
+		results := make([]Result, len(subdomains))
+		for i, sub := range subdomains {
+			results[i] = s.jsonResult(sub)
+		}
+		encoder := json.NewEncoder(writer)
+		if s.pretty {
+			encoder.SetIndent("", "  ")
+		}
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	case s.hostsFormat:
+		s.writeHosts(writer, subdomains)
+	case s.nsCandidates:
+		s.writeNSCandidates(writer, subdomains)
+	case s.freq:
+		s.writeFreq(writer, subdomains)
+	default:
+		var lines []string
+		for _, sub := range subdomains {
+			lines = append(lines, s.formatResult(sub)...)
+		}
+		s.writeLines(writer, lines)
+	}
+
+	return writer.Flush()
+}
+
+// Line terminators accepted by -line-ending.
+const (
+	lineEndingLF   = "lf"
+	lineEndingCRLF = "crlf"
+	lineEndingNone = "none" // LF between lines, nothing after the last one
+)
+
+// eol returns the line terminator for text output.
+func (s *SubHunter) eol() string {
+	if s.lineEnding == lineEndingCRLF {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// writeHeaderComment writes the -header-comment provenance line of a text
+// output file. Tools reading such lists commonly skip "#" lines, and so
+// does -merge, which writes a fresh header for the merged results.
+func (s *SubHunter) writeHeaderComment(w io.Writer, count int) {
+	fmt.Fprintf(w, "# SubHunter v%s — %s — %s — %d subdomains%s",
+		version, s.headerTarget, time.Now().UTC().Format(time.RFC3339), count, s.eol())
+}
+
+// writeLines writes text lines with the configured line terminator.
+func (s *SubHunter) writeLines(w io.Writer, lines []string) {
+	for i, line := range lines {
+		io.WriteString(w, line)
+		if i < len(lines)-1 || s.lineEnding != lineEndingNone {
+			io.WriteString(w, s.eol())
+		}
+	}
+}
+
+// csvHeader lists the columns written by writeCSV; they mirror the JSON
+// fields of Result.
+var csvHeader = []string{"subdomain", "cert_id", "cert_count", "ips", "first_seen", "asn", "as_org", "open_ports"}
+
+// writeCSV writes one row per subdomain with a header row. Columns for data
+// that was not collected are left empty; multiple addresses and ports are
+// separated by spaces.
+func (s *SubHunter) writeCSV(w io.Writer, subdomains []string) error {
+	writer := csv.NewWriter(w)
+	writer.UseCRLF = s.lineEnding == lineEndingCRLF
+	writer.Write(csvHeader)
+	for _, sub := range subdomains {
+		result := s.buildResult(sub)
+		var ports []string
+		for _, port := range result.OpenPorts {
+			ports = append(ports, strconv.Itoa(port))
+		}
+		row := []string{result.Subdomain, "", "", strings.Join(result.IPs, " "), result.FirstSeen, "", result.ASOrg, strings.Join(ports, " ")}
+		if result.CertID > 0 {
+			row[1] = strconv.FormatInt(result.CertID, 10)
+		}
+		if result.CertCount > 0 {
+			row[2] = strconv.Itoa(result.CertCount)
+		}
+		if result.ASN > 0 {
+			row[5] = strconv.FormatUint(uint64(result.ASN), 10)
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeFreq writes each subdomain after the number of certificates naming
+// it, most certified first and alphabetically among equal counts.
+func (s *SubHunter) writeFreq(w io.Writer, subdomains []string) {
+	ordered := slices.Clone(subdomains)
+	counts := make(map[string]int, len(ordered))
+	for _, sub := range ordered {
+		counts[sub] = s.certCount(sub)
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if counts[ordered[i]] != counts[ordered[j]] {
+			return counts[ordered[i]] > counts[ordered[j]]
+		}
+		return ordered[i] < ordered[j]
+	})
+
+	lines := make([]string, len(ordered))
+	for i, sub := range ordered {
+		lines[i] = fmt.Sprintf("%d %s", counts[sub], s.encodeName(sub))
+	}
+	s.writeLines(w, lines)
+}
+
+// writeMarkdown writes the results as a Markdown table for reports and
+// tickets. The IP and port columns are always present and stay empty for
+// hosts that were not resolved or scanned.
+func (s *SubHunter) writeMarkdown(w io.Writer, subdomains []string) {
+	lines := []string{
+		"| Subdomain | Resolved IPs | Open Ports |",
+		"| --- | --- | --- |",
+	}
+	for _, sub := range subdomains {
+		result := s.buildResult(sub)
+		var ports []string
+		for _, port := range result.OpenPorts {
+			ports = append(ports, strconv.Itoa(port))
+		}
+		lines = append(lines, fmt.Sprintf("| %s | %s | %s |", result.Subdomain, strings.Join(result.IPs, ", "), strings.Join(ports, ", ")))
+	}
+	s.writeLines(w, lines)
+}
+
+// writeHosts writes resolved subdomains as hosts-file lines, one per
+// address with every name pointing at it. Addresses keep the order in which
+// their first name appears; hosts without addresses are skipped.
+func (s *SubHunter) writeHosts(w io.Writer, subdomains []string) {
+	var order, lines []string
+	names := make(map[string][]string)
+	for _, sub := range subdomains {
+		for _, ip := range s.resolvedIPs(sub) {
+			addr := ip.String()
+			if _, ok := names[addr]; !ok {
+				order = append(order, addr)
+			}
+			names[addr] = append(names[addr], s.encodeName(sub))
+		}
+	}
+
+	for _, addr := range order {
+		lines = append(lines, addr+"\t"+strings.Join(names[addr], " "))
+	}
+	s.writeLines(w, lines)
+}
+
+// writeStreamed writes a single result for the streaming writers: a line of
+// text, or one JSON object per record (indented with -pretty). The record is
+// written with a single Write so it stays intact on a shared writer.
+func (s *SubHunter) writeStreamed(w io.Writer, subdomain string) {
+	var buf bytes.Buffer
+
+	if !s.jsonOutput {
+		for _, line := range s.formatResult(subdomain) {
+			buf.WriteString(line + s.eol())
+		}
+	} else {
+		var data []byte
+		if s.pretty {
+			data, _ = json.MarshalIndent(s.jsonResult(subdomain), "", "  ")
+		} else {
+			data, _ = json.Marshal(s.jsonResult(subdomain))
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	w.Write(buf.Bytes())
+}
+
+// isRemoteOutput reports whether dest is a URL rather than a local path.
+func isRemoteOutput(dest string) bool {
+	return strings.Contains(dest, "://")
+}
+
+// checkCompression rejects output names asking for a compression format
+// that is not supported. ".gz" outputs are gzip-compressed.
+func checkCompression(dest string) error {
+	if strings.EqualFold(path.Ext(dest), ".zst") {
+		return fmt.Errorf("zstd output is not supported, use .gz instead: %s", dest)
+	}
+	return nil
+}
+
+func isCompressedOutput(dest string) bool {
+	return strings.EqualFold(path.Ext(dest), ".gz")
+}
+
+// openOutput returns a writer for an output destination, gzip-compressing
+// it when the name ends in ".gz".
+func (s *SubHunter) openOutput(dest string) (io.WriteCloser, error) {
+	if err := checkCompression(dest); err != nil {
+		return nil, err
+	}
+	out, err := s.openDestination(dest)
+	if err != nil {
+		return nil, err
+	}
+	if isCompressedOutput(dest) {
+		return &gzipOutput{Writer: gzip.NewWriter(out), out: out}, nil
+	}
+	return out, nil
+}
+
+// gzipOutput compresses into an underlying output and closes both, the
+// compressor first so its trailer is written.
+type gzipOutput struct {
+	*gzip.Writer
+	out io.WriteCloser
+}
+
+func (g *gzipOutput) Close() error {
+	err := g.Writer.Close()
+	if closeErr := g.out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// openDestination opens an output destination. Local paths are files;
+// http(s) URLs are uploaded with a PUT when the writer is closed.
+func (s *SubHunter) openDestination(dest string) (io.WriteCloser, error) {
+	switch {
+	case strings.HasPrefix(dest, "http://"), strings.HasPrefix(dest, "https://"):
+		return &httpPutWriter{hunter: s, url: dest, contentType: s.contentType(dest)}, nil
+	case strings.HasPrefix(dest, "s3://"):
+		return nil, fmt.Errorf("s3:// destinations need AWS request signing; use a pre-signed https:// URL instead")
+	default:
+		return os.Create(dest)
+	}
+}
+
+// contentType returns the media type of an upload to dest: that of its
+// result format, or gzip for a compressed one.
+func (s *SubHunter) contentType(dest string) string {
+	if isCompressedOutput(dest) {
+		return "application/gzip"
+	}
+	switch s.formatFor(dest) {
+	case formatJSON:
+		return "application/json"
+	case formatCSV:
+		return "text/csv; charset=utf-8"
+	case formatMarkdown:
+		return "text/markdown; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// httpPutWriter buffers output in memory and uploads it on Close, so the
+// upload can be retried as a whole.
+type httpPutWriter struct {
+	hunter      *SubHunter
+	url         string
+	contentType string
+	buf         bytes.Buffer
+}
+
+func (w *httpPutWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *httpPutWriter) Close() error {
+	var lastErr error
+	for attempt := 1; attempt <= w.hunter.maxRetries; attempt++ {
+		if attempt > 1 {
+			if !w.hunter.takeRetry() {
+				break
+			}
+			w.hunter.log("retry", fmt.Sprintf("Upload attempt %d/%d for", attempt, w.hunter.maxRetries), w.url)
+			if !w.hunter.wait(w.hunter.backoff(attempt)) {
+				return fmt.Errorf("upload %w, last error: %v", ErrInterrupted, lastErr)
+			}
+		}
+
+		req, err := http.NewRequest(http.MethodPut, w.url, bytes.NewReader(w.buf.Bytes()))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", w.contentType)
+
+		resp, _, err := w.hunter.do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			lastErr = ErrHTTPStatus{resp.StatusCode}
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("upload failed: %v", lastErr)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUploadContentTypeAndRetry(t *testing.T) {
+	tests := []struct {
+		path        string
+		contentType string
+	}{
+		{"/subs.txt", "text/plain; charset=utf-8"},
+		{"/subs", "text/plain; charset=utf-8"},
+		{"/subs.json", "application/json"},
+		{"/subs.csv", "text/csv; charset=utf-8"},
+		{"/subs.md", "text/markdown; charset=utf-8"},
+		{"/subs.json.gz", "application/gzip"},
+	}
+	for _, tt := range tests {
+		var requests atomic.Int32
+		var contentType string
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPut {
+				t.Errorf("%s: method %s, want PUT", tt.path, r.Method)
+			}
+			if requests.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			contentType = r.Header.Get("Content-Type")
+			body, _ = io.ReadAll(r.Body)
+		}))
+
+		s := testHunter(server.URL)
+		err := s.saveToFile([]string{"a.example.com"}, server.URL+tt.path)
+		server.Close()
+		if err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+			continue
+		}
+
+		if n := requests.Load(); n != 2 {
+			t.Errorf("%s: %d requests, want a retry after the 503", tt.path, n)
+		}
+		if contentType != tt.contentType {
+			t.Errorf("%s: Content-Type %q, want %q", tt.path, contentType, tt.contentType)
+		}
+		if strings.HasSuffix(tt.path, ".gz") {
+			zr, err := gzip.NewReader(strings.NewReader(string(body)))
+			if err != nil {
+				t.Errorf("%s: body is not gzip: %v", tt.path, err)
+				continue
+			}
+			body, _ = io.ReadAll(zr)
+		}
+		if !strings.Contains(string(body), "a.example.com") {
+			t.Errorf("%s: uploaded %q", tt.path, body)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// validateMatchPattern checks a -match-pattern value: a crt.sh identity
+// search using SQL LIKE wildcards, "%" for any run of characters and "_"
+// for a single one. It returns the pattern lowercased.
+func validateMatchPattern(pattern string) (string, error) {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	for _, c := range pattern {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.ContainsRune(".-%_*", c)) {
+			return "", fmt.Errorf("invalid character %q in match pattern", c)
+		}
+	}
+	if !strings.Contains(pattern, ".") || strings.Trim(pattern, "%_.") == "" {
+		return "", fmt.Errorf("match pattern %q is too broad, it needs some literal labels", pattern)
+	}
+	return pattern, nil
+}
+
+// matchPatternRegexp translates a validated LIKE pattern into an anchored
+// regular expression over hostnames.
+func matchPatternRegexp(pattern string) *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, c := range pattern {
+		switch c {
+		case '%':
+			expr.WriteString(`[a-z0-9.*_-]*`)
+		case '_':
+			expr.WriteString(`[a-z0-9.*_-]`)
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String())
+}
+
+// queryPattern runs a raw crt.sh identity search and keeps the hostnames
+// that match the pattern itself, since crt.sh also matches other identity
+// fields.
+func (s *SubHunter) queryPattern(pattern string) ([]string, error) {
+	query := fmt.Sprintf("/?q=%s&output=json", url.QueryEscape(pattern))
+	results, err := s.fetchCertificates(query, pattern, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	match := matchPatternRegexp(pattern)
+	var hosts []string
+	for _, host := range s.extractHostnames(results) {
+		if match.MatchString(host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return s.filterResults(hosts), nil
+}
+
+// processPattern enumerates the hostnames matching a -match-pattern.
+func (s *SubHunter) processPattern(pattern string, showResults bool) []string {
+	hosts, err := s.queryPattern(pattern)
+	if err != nil {
+		s.log("error", "Failed to query pattern", err.Error())
+		return nil
+	}
+
+	s.totalFound = len(hosts)
+	if len(hosts) == 0 {
+		s.log("warn", "No hostnames match", pattern)
+		return nil
+	}
+
+	s.log("found", fmt.Sprintf("Found %d matching hostnames", len(hosts)), "")
+	if showResults {
+		for _, host := range hosts {
+			s.printResult(host)
+		}
+	}
+	return hosts
+}
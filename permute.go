@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultPermuteWords are common environment and role tokens used to build
+// candidate subdomains from discovered ones.
+var defaultPermuteWords = []string{
+	"dev", "development", "staging", "stage", "test", "qa", "uat", "prod",
+	"preprod", "beta", "demo", "sandbox", "api", "admin", "internal", "old",
+	"new", "backup", "v2",
+}
+
+// loadPermuteWords reads words from a file, or splits value as a
+// comma-separated list if no such file exists.
+func loadPermuteWords(value string) ([]string, error) {
+	if _, err := os.Stat(value); err == nil {
+		return readLines(value)
+	}
+	var words []string
+	for _, word := range strings.Split(value, ",") {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			words = append(words, word)
+		}
+	}
+	return words, nil
+}
+
+// permutations generates likely-but-unseen subdomains by varying the
+// leftmost label of each discovered subdomain: swapping it for a word,
+// joining it with a word, and numbering it (dev -> dev2).
+func (s *SubHunter) permutations(subdomains []string) []string {
+	known := make(mapSet, len(subdomains))
+	for _, sub := range subdomains {
+		known.add(sub)
+	}
+
+	candidates := make(mapSet)
+	var result []string
+	add := func(name string) {
+		if !known[name] && s.isValidSubdomain(name) && candidates.add(name) {
+			result = append(result, name)
+		}
+	}
+
+	for _, sub := range subdomains {
+		label, parent, ok := strings.Cut(sub, ".")
+		if !ok || sub == registrableDomain(sub) {
+			continue
+		}
+
+		for _, word := range s.permuteWords {
+			add(word + "." + parent)
+			add(label + "-" + word + "." + parent)
+			add(word + "-" + label + "." + parent)
+		}
+		add(nextNumbered(label) + "." + parent)
+	}
+
+	s.sortResults(result)
+	return result
+}
+
+// nextNumbered increments a trailing number in label, or appends 2.
+func nextNumbered(label string) string {
+	base := strings.TrimRight(label, "0123456789")
+	if base == label {
+		return label + "2"
+	}
+	n, err := strconv.Atoi(label[len(base):])
+	if err != nil {
+		return label + "2"
+	}
+	return base + strconv.Itoa(n+1)
+}
+
+// addPermutations extends the result set with generated candidates.
+func (s *SubHunter) addPermutations(subdomains []string) []string {
+	candidates := s.permutations(subdomains)
+	s.log("info", fmt.Sprintf("Generated %d permutation candidates", len(candidates)), "")
+
+	merged := append(append([]string{}, subdomains...), candidates...)
+	s.sortResults(merged)
+	return merged
+}
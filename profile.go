@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+)
+
+// profiler writes the -cpuprofile and -memprofile data of a run. A nil
+// profiler is valid and does nothing, so runs without profiling pay for a
+// nil check only.
+type profiler struct {
+	cpu     *os.File
+	memPath string
+	once    sync.Once
+}
+
+// startProfiling starts CPU profiling to cpuPath and arranges for a heap
+// profile to be written to memPath when the profiler stops. Either path may
+// be empty; with both empty it returns nil.
+func startProfiling(cpuPath, memPath string) (*profiler, error) {
+	if cpuPath == "" && memPath == "" {
+		return nil, nil
+	}
+	p := &profiler{memPath: memPath}
+	if cpuPath != "" {
+		f, err := os.Create(cpuPath)
+		if err != nil {
+			return nil, fmt.Errorf("creating CPU profile: %v", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("starting CPU profile: %v", err)
+		}
+		p.cpu = f
+	}
+	return p, nil
+}
+
+// stop ends CPU profiling and writes the heap profile. Only the first call
+// has an effect, so it is safe from both the normal and the signal path.
+func (p *profiler) stop() {
+	if p == nil {
+		return
+	}
+	p.once.Do(func() {
+		if p.cpu != nil {
+			pprof.StopCPUProfile()
+			if err := p.cpu.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "[ERR] writing CPU profile: %v\n", err)
+			}
+		}
+		if p.memPath != "" {
+			if err := writeHeapProfile(p.memPath); err != nil {
+				fmt.Fprintf(os.Stderr, "[ERR] writing memory profile: %v\n", err)
+			}
+		}
+	})
+}
+
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	// collect garbage first so the profile shows live memory only
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// stopOnInterrupt writes the profiles and exits when the run is
+// interrupted. The first skip interrupts are left to another handler, as
+// with -batch-size, where the first Ctrl-C stops the run gracefully.
+func (p *profiler) stopOnInterrupt(skip int) {
+	if p == nil {
+		return
+	}
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		for n := 0; ; n++ {
+			<-interrupts
+			if n < skip {
+				continue
+			}
+			p.stop()
+			os.Exit(130)
+		}
+	}()
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// rng is the single source of randomness for the whole program. Every
+// randomized behavior must draw from it (never the global math/rand) so that
+// a run can be reproduced with -seed. Consumers:
+//
+//   - -ramp, which staggers the start of the concurrent workers
+var (
+	rngMu sync.Mutex
+	rng   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// seedRandom reseeds rng. A zero seed picks a time-based one, which is
+// returned so it can be reported and reused.
+func seedRandom(seed int64) int64 {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rngMu.Lock()
+	defer rngMu.Unlock()
+	rng.Seed(seed)
+	return seed
+}
+
+// randomInt63n returns a random value in [0, n) from rng.
+func randomInt63n(n int64) int64 {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+	return rng.Int63n(n)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// rawLine is one domain's -raw response when several domains are queried.
+type rawLine struct {
+	Domain   string          `json:"domain"`
+	Response json.RawMessage `json:"response"`
+}
+
+// fetchRaw downloads a crt.sh JSON response without decoding it, retrying
+// like fetchCertificates. HTML error pages and invalid JSON are retried too,
+// so only real API responses are saved.
+func (s *SubHunter) fetchRaw(path, target string) ([]byte, error) {
+	var raw []byte
+	err := s.fetchWithRetry(path, target, func(body io.Reader) error {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		trimmed := bytes.TrimSpace(data)
+		if bytes.HasPrefix(trimmed, []byte("<")) {
+			return ErrHTMLResponse
+		}
+		if !json.Valid(trimmed) {
+			return ErrDecode{fmt.Errorf("invalid JSON")}
+		}
+		raw = data
+		return nil
+	})
+	return raw, err
+}
+
+// saveRaw writes the unmodified crt.sh response for domain to the -raw
+// output. With several domains each response is compacted onto one NDJSON
+// line that names its domain, so the blocks can be told apart.
+func (s *SubHunter) saveRaw(domain string) error {
+	raw, err := s.fetchRaw(s.crtQueryPath(domain), domain)
+	if err != nil {
+		return err
+	}
+
+	if s.rawLines {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, raw); err != nil {
+			return err
+		}
+		line, err := json.Marshal(rawLine{Domain: domain, Response: compact.Bytes()})
+		if err != nil {
+			return err
+		}
+		raw = append(line, '\n')
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.rawOut.Write(raw)
+	return err
+}
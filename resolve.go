@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// acquireNet blocks until a network slot is free. Every network operation,
+// HTTP or DNS, holds a slot while it runs.
+func (s *SubHunter) acquireNet() {
+	if s.netSlots != nil {
+		s.netSlots <- struct{}{}
+	}
+}
+
+func (s *SubHunter) releaseNet() {
+	if s.netSlots != nil {
+		<-s.netSlots
+	}
+}
+
+// lookupIPs resolves a hostname using the configured timeout, over DoH when
+// -doh is set.
+func (s *SubHunter) lookupIPs(host string) ([]net.IP, error) {
+	ctx := context.Background()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	if s.dohURL != "" {
+		ips, err := s.dohLookupIPs(ctx, host)
+		if err == nil {
+			if len(ips) == 0 {
+				return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+			}
+			return ips, nil
+		}
+		s.dohFailed(err)
+	}
+
+	s.acquireNet()
+	defer s.releaseNet()
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		ips[i] = addr.IP
+	}
+	return ips, nil
+}
+
+// lookupNS returns the name servers of a domain, without trailing dots,
+// using the configured timeout and DoH when -doh is set.
+func (s *SubHunter) lookupNS(domain string) ([]string, error) {
+	ctx := context.Background()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	if s.dohURL != "" {
+		hosts, err := s.dohLookupNS(ctx, domain)
+		if err == nil {
+			return hosts, nil
+		}
+		s.dohFailed(err)
+	}
+
+	s.acquireNet()
+	defer s.releaseNet()
+
+	nameservers, err := net.DefaultResolver.LookupNS(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	hosts := make([]string, len(nameservers))
+	for i, ns := range nameservers {
+		hosts[i] = strings.TrimSuffix(strings.ToLower(ns.Host), ".")
+	}
+	return hosts, nil
+}
+
+// apexExists reports whether a domain still has NS records or addresses,
+// using the configured timeout for each lookup.
+func (s *SubHunter) apexExists(domain string) bool {
+	if nameservers, err := s.lookupNS(domain); err == nil && len(nameservers) > 0 {
+		return true
+	}
+
+	ips, err := s.lookupIPs(domain)
+	return err == nil && len(ips) > 0
+}
+
+// resolvableApexes drops the domains that no longer exist in DNS, checking
+// them with the worker pool. Order is preserved.
+func (s *SubHunter) resolvableApexes(domains []string) []string {
+	s.log("run", fmt.Sprintf("Checking %d domains for NS/A records", len(domains)), "")
+
+	exists := make([]bool, len(domains))
+	semaphore := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+
+	for i, domain := range domains {
+		wg.Add(1)
+		go func(idx int, d string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			exists[idx] = s.apexExists(d)
+		}(i, strings.ToLower(strings.TrimSpace(domain)))
+	}
+	wg.Wait()
+
+	var kept []string
+	for i, domain := range domains {
+		if !exists[i] {
+			s.log("warn", "Skipping domain without NS or A records", domain)
+			continue
+		}
+		kept = append(kept, domain)
+	}
+	return kept
+}
+
+// resolveAll resolves subdomains with the worker pool and returns, in the
+// original order, those that resolve and pass the IP range filters.
+func (s *SubHunter) resolveAll(subdomains []string) []string {
+	s.log("run", fmt.Sprintf("Resolving %d subdomains", len(subdomains)), "")
+
+	resolved := make([][]net.IP, len(subdomains))
+	semaphore := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+
+	for i, sub := range subdomains {
+		wg.Add(1)
+		go func(idx int, host string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			ips, err := s.lookupIPs(host)
+			if err == nil {
+				resolved[idx] = ips
+			}
+		}(i, sub)
+	}
+	wg.Wait()
+
+	var live []string
+	for i, sub := range subdomains {
+		ips := resolved[i]
+		if len(ips) == 0 || !s.ipAllowed(ips) {
+			continue
+		}
+		s.mu.Lock()
+		s.ips[sub] = ips
+		s.mu.Unlock()
+		live = append(live, sub)
+	}
+
+	s.log("found", fmt.Sprintf("%d of %d subdomains resolved", len(live), len(subdomains)), "")
+	return live
+}
+
+// ipAllowed applies -ip-range and -ip-range-exclude to a host's addresses.
+func (s *SubHunter) ipAllowed(ips []net.IP) bool {
+	if len(s.ipRanges) > 0 && !anyIPInRanges(ips, s.ipRanges) {
+		return false
+	}
+	return !anyIPInRanges(ips, s.ipExcludes)
+}
+
+func anyIPInRanges(ips []net.IP, ranges []*net.IPNet) bool {
+	for _, ip := range ips {
+		for _, network := range ranges {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseCIDRs parses a comma-separated list of CIDR ranges.
+func parseCIDRs(value string) ([]*net.IPNet, error) {
+	var ranges []*net.IPNet
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", field)
+		}
+		ranges = append(ranges, network)
+	}
+	return ranges, nil
+}
+
+// resolvedIPs returns the addresses recorded for a subdomain by resolveAll.
+func (s *SubHunter) resolvedIPs(subdomain string) []net.IP {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ips[subdomain]
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// defaultBackoffMax is the default -backoff-max.
+const defaultBackoffMax = 30 * time.Second
+
+// backoff returns how long to wait before the given retry attempt, capped
+// at -backoff-max so many retries keep a predictable worst case.
+func (s *SubHunter) backoff(attempt int) time.Duration {
+	delay := time.Duration(attempt) * time.Second // Backoff: 2s, 3s, ...
+	delay = time.Duration(float64(delay) * s.retryMultiplier)
+	if s.backoffMax > 0 && delay > s.backoffMax {
+		delay = s.backoffMax
+	}
+	return delay
+}
+
+// takeRetry consumes one retry from the run-wide -retry-budget and reports
+// whether the retry may go ahead. Without a budget retries are unlimited.
+func (s *SubHunter) takeRetry() bool {
+	if s.retryBudget == nil {
+		return true
+	}
+	if s.retryBudget.Add(-1) >= 0 {
+		return true
+	}
+	if s.retryBudgetSpent.CompareAndSwap(false, true) {
+		s.log("warn", "Retry budget exhausted, remaining failures will not be retried", "")
+	}
+	return false
+}
+
+// newRetryBudget returns a shared counter holding n retries, or nil for an
+// unlimited budget.
+func newRetryBudget(n int) *atomic.Int64 {
+	if n <= 0 {
+		return nil
+	}
+	budget := new(atomic.Int64)
+	budget.Store(int64(n))
+	return budget
+}
+
+// parseRetryAfter interprets a Retry-After header, which may be either a
+// number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	when, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	delay := when.Sub(now)
+	if delay < 0 {
+		delay = 0
+	}
+	return delay, true
+}
+
+// retryAfterDelay returns the server-requested delay for a throttled
+// response, capped so a huge value cannot stall the run.
+func (s *SubHunter) retryAfterDelay(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if !ok {
+		return 0, false
+	}
+	if s.retryAfterMax > 0 && delay > s.retryAfterMax {
+		delay = s.retryAfterMax
+	}
+	return delay, true
+}
+
+// startCooldown pauses every worker's crt.sh queries for d after a 429,
+// since one throttled worker means the others are about to be throttled
+// too. An already running longer cooldown is kept.
+func (s *SubHunter) startCooldown(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	until := time.Now().Add(d)
+
+	s.cooldownMu.Lock()
+	extended := until.After(s.cooldownUntil)
+	if extended {
+		s.cooldownUntil = until
+	}
+	s.cooldownMu.Unlock()
+
+	if extended {
+		s.log("warn", "Rate limited by crt.sh, pausing all workers for", d.Round(time.Second).String())
+	}
+}
+
+// awaitCooldown blocks until any global cooldown has passed. It reports
+// false if the run was interrupted meanwhile.
+func (s *SubHunter) awaitCooldown() bool {
+	for {
+		s.cooldownMu.Lock()
+		remaining := time.Until(s.cooldownUntil)
+		s.cooldownMu.Unlock()
+		if remaining <= 0 {
+			return true
+		}
+		// the cooldown may be extended while waiting, so check again
+		if !s.wait(remaining) {
+			return false
+		}
+	}
+}
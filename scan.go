@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultScanTimeout bounds each -scan-ports connection attempt.
+const defaultScanTimeout = 2 * time.Second
+
+// scanAll tries a TCP connect to each -scan-ports port of every resolved
+// subdomain, using the worker pool so at most s.concurrency probes run at
+// once, and records the open ones.
+func (s *SubHunter) scanAll(subdomains []string) {
+	s.log("run", fmt.Sprintf("Scanning %d ports on %d hosts", len(s.scanPorts), len(subdomains)), "")
+
+	semaphore := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+	hosts := 0
+
+	for _, sub := range subdomains {
+		ips := s.resolvedIPs(sub)
+		if len(ips) == 0 {
+			continue
+		}
+		for _, port := range s.scanPorts {
+			wg.Add(1)
+			go func(host string, ip net.IP, port int) {
+				defer wg.Done()
+				semaphore <- struct{}{}
+				defer func() { <-semaphore }()
+
+				if !s.portOpen(ip, port) {
+					return
+				}
+				s.mu.Lock()
+				if len(s.openPorts[host]) == 0 {
+					hosts++
+				}
+				s.openPorts[host] = append(s.openPorts[host], port)
+				s.mu.Unlock()
+			}(sub, ips[0], port)
+		}
+	}
+	wg.Wait()
+
+	s.log("found", fmt.Sprintf("%d of %d hosts have open ports", hosts, len(subdomains)), "")
+}
+
+// portOpen reports whether a TCP connection to ip:port succeeds within
+// s.scanTimeout.
+func (s *SubHunter) portOpen(ip net.IP, port int) bool {
+	s.acquireNet()
+	defer s.releaseNet()
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip.String(), strconv.Itoa(port)), s.scanTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// hostOpenPorts returns the open ports recorded for a subdomain by scanAll,
+// in the order of -scan-ports.
+func (s *SubHunter) hostOpenPorts(subdomain string) []int {
+	s.mu.Lock()
+	open := make(map[int]bool, len(s.openPorts[subdomain]))
+	for _, port := range s.openPorts[subdomain] {
+		open[port] = true
+	}
+	s.mu.Unlock()
+
+	var ports []int
+	for _, port := range s.scanPorts {
+		if open[port] {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
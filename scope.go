@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrOutOfScope is returned for a query on a domain outside the -scope
+// allowlist.
+var ErrOutOfScope = errors.New("domain is out of scope")
+
+// loadScope reads a -scope file of allowed apex domains, one per line.
+func loadScope(filename string) (map[string]bool, error) {
+	lines, err := readLines(filename)
+	if err != nil {
+		return nil, err
+	}
+	scope := make(map[string]bool, len(lines))
+	for _, line := range lines {
+		if apex := normalizeSubdomain(line); apex != "" {
+			scope[apex] = true
+		}
+	}
+	if len(scope) == 0 {
+		return nil, fmt.Errorf("scope file %s lists no domains", filename)
+	}
+	return scope, nil
+}
+
+// inScope reports whether domain is allowed by -scope: it is a scope entry
+// or lies under one. Without -scope every domain is allowed.
+func (s *SubHunter) inScope(domain string) bool {
+	if s.scope == nil {
+		return true
+	}
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	return s.scope[domain] || coveringDomain(domain, s.scope) != ""
+}
+
+// scopedDomains drops, with an error logged for each, the domains outside
+// -scope. Order is preserved.
+func (s *SubHunter) scopedDomains(domains []string) []string {
+	if s.scope == nil {
+		return domains
+	}
+	var kept []string
+	for _, d := range domains {
+		if !s.inScope(d) {
+			s.log("error", "Refusing out-of-scope domain", d)
+			continue
+		}
+		kept = append(kept, d)
+	}
+	return kept
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// selftestDomain is the known-good name used by the self-test: crt.sh's own
+// domain resolves and has certificates as long as crt.sh itself is up.
+const selftestDomain = "crt.sh"
+
+// selftestCheck is one self-test step. run returns a short description of
+// what it found.
+type selftestCheck struct {
+	name string
+	run  func() (string, error)
+}
+
+// selftestChecks returns the checks to run, in order.
+func (s *SubHunter) selftestChecks() []selftestCheck {
+	checks := []selftestCheck{
+		{name: "DNS resolution", run: func() (string, error) {
+			if s.dohURL != "" {
+				// no fallback here: the point is to check the endpoint
+				ips, err := s.dohLookupIPs(context.Background(), selftestDomain)
+				if err != nil {
+					return "", err
+				}
+				if len(ips) == 0 {
+					return "", fmt.Errorf("no addresses for %s over DoH", selftestDomain)
+				}
+				return fmt.Sprintf("%s -> %s (DoH)", selftestDomain, ips[0]), nil
+			}
+			ips, err := s.lookupIPs(selftestDomain)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s -> %s", selftestDomain, ips[0]), nil
+		}},
+	}
+
+	if proxy := s.proxyURL(); proxy != nil {
+		checks = append(checks, selftestCheck{name: "Proxy", run: func() (string, error) {
+			host := proxy.Host
+			if proxy.Port() == "" {
+				host = net.JoinHostPort(proxy.Hostname(), "80")
+			}
+			conn, err := net.DialTimeout("tcp", host, s.selftestTimeout())
+			if err != nil {
+				return "", err
+			}
+			conn.Close()
+			return proxy.Redacted(), nil
+		}})
+	}
+
+	for _, endpoint := range s.endpoints {
+		endpoint := endpoint
+		checks = append(checks, selftestCheck{name: "crt.sh API", run: func() (string, error) {
+			req, err := s.newCRTRequest(endpoint + fmt.Sprintf("/?q=%s&output=json", selftestDomain))
+			if err != nil {
+				return "", err
+			}
+			var results []CRTResponse
+			resp, err := s.send(req, func(resp *http.Response) error {
+				if resp.StatusCode != http.StatusOK {
+					return ErrHTTPStatus{resp.StatusCode}
+				}
+				var err error
+				results, err = decodeCertificates(resp.Body, nil)
+				return err
+			})
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", endpoint, err)
+			}
+			return fmt.Sprintf("%s: HTTP %d, %d certificates", endpoint, resp.StatusCode, len(results)), nil
+		}})
+	}
+
+	return checks
+}
+
+// proxyURL returns the proxy the HTTP client uses for the first crt.sh
+// endpoint, if any.
+func (s *SubHunter) proxyURL() *url.URL {
+	req, err := http.NewRequest("GET", s.endpoints[0]+"/", nil)
+	if err != nil {
+		return nil
+	}
+	proxy, err := http.ProxyFromEnvironment(req)
+	if err != nil {
+		return nil
+	}
+	return proxy
+}
+
+func (s *SubHunter) selftestTimeout() time.Duration {
+	if s.timeout > 0 {
+		return s.timeout
+	}
+	return defaultScanTimeout
+}
+
+// runSelftest implements the selftest command: it checks that everything a
+// scan depends on works, so an empty result can be told apart from a broken
+// setup.
+func runSelftest(args []string) {
+	fs := newFlagSet("selftest", "selftest [flags]")
+	timeout := fs.Int("t", 15, "timeout in seconds for each check (0 = no timeout)")
+	silent := fs.Bool("silent", false, "only print failing checks")
+	doh := fs.String("doh", "", "check DNS resolution over this DNS-over-HTTPS endpoint")
+	crtshURL := fs.String("crtsh-url", defaultEndpoint, "comma-separated crt.sh base URLs to check, as given to enum")
+	fs.Parse(args)
+
+	printBanner(*silent)
+
+	hunter := NewSubHunter(*timeout, 1, *silent)
+	hunter.dohURL = *doh
+	var err error
+	hunter.endpoints, err = parseEndpoints(*crtshURL)
+	exitOnError(err)
+	if auth := os.Getenv(basicAuthEnv); auth != "" {
+		user, pass, err := parseBasicAuth(auth)
+		exitOnError(err)
+		hunter.basicAuthUser, hunter.basicAuthPass = user, pass
+	}
+	hunter.apiToken = os.Getenv(apiTokenEnv)
+
+	failed := false
+	for _, check := range hunter.selftestChecks() {
+		start := time.Now()
+		detail, err := check.run()
+		latency := time.Since(start).Round(time.Millisecond)
+
+		if err != nil {
+			fmt.Printf("%s[FAIL]%s %-16s %6s  %s\n", pink, reset, check.name, latency, err)
+			failed = true
+			continue
+		}
+		if !*silent {
+			fmt.Printf("%s[PASS]%s %-16s %6s  %s\n", pink, reset, check.name, latency, detail)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
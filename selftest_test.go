@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSelftestChecksEachEndpoint(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id":1,"name_value":"crt.sh"}]`)
+	}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+
+	s := testHunter(up.URL)
+	s.endpoints = []string{up.URL, down.URL}
+
+	var results []string
+	for _, check := range s.selftestChecks() {
+		if check.name != "crt.sh API" {
+			continue
+		}
+		detail, err := check.run()
+		if err != nil {
+			detail = "FAIL " + err.Error()
+		}
+		results = append(results, detail)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("got %d crt.sh checks, want one per endpoint: %q", len(results), results)
+	}
+	if !strings.HasPrefix(results[0], up.URL+": HTTP 200") {
+		t.Errorf("first endpoint: %q, want a pass", results[0])
+	}
+	if !strings.HasPrefix(results[1], "FAIL "+down.URL) {
+		t.Errorf("second endpoint: %q, want a failure", results[1])
+	}
+}
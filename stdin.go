@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+)
+
+// stdinRequest is one input line of -stdin-json mode.
+type stdinRequest struct {
+	Domain string `json:"domain"`
+}
+
+// stdinRecord is one output line of -stdin-json mode: a result tagged with
+// the domain it was found for, or the error that domain failed with.
+type stdinRecord struct {
+	Domain string `json:"domain"`
+	*Result
+	Error string `json:"error,omitempty"`
+}
+
+// processStdinJSON enumerates the domains of NDJSON requests read from r
+// until EOF, writing one NDJSON record per result as soon as each domain is
+// done. Domains are normalized, capped and counted like -l entries and
+// processed with the worker pool, so -c, -net-concurrency and the retry
+// settings apply across the whole stream.
+func (s *SubHunter) processStdinJSON(r io.Reader) {
+	semaphore := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var req stdinRequest
+		if err := json.Unmarshal([]byte(line), &req); err != nil || strings.TrimSpace(req.Domain) == "" {
+			s.writeStdinRecord(stdinRecord{Domain: req.Domain, Error: "invalid request: " + line})
+			continue
+		}
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			domain, subdomains, err := s.enumerateDomain(req.Domain)
+			if domain == "" && err == nil {
+				s.writeStdinRecord(stdinRecord{Domain: req.Domain, Error: "invalid request: " + line})
+				return
+			}
+			if err != nil {
+				s.writeStdinRecord(stdinRecord{Domain: domain, Error: err.Error()})
+				return
+			}
+			subdomains = s.capDomain(domain, subdomains)
+
+			for _, sub := range subdomains {
+				result := s.jsonResult(sub)
+				s.writeStdinRecord(stdinRecord{Domain: domain, Result: &result})
+			}
+		}()
+	}
+	if err := scanner.Err(); err != nil {
+		s.writeStdinRecord(stdinRecord{Error: "reading stdin: " + err.Error()})
+	}
+	wg.Wait()
+}
+
+// writeStdinRecord writes a record as a single line to stdout, which is
+// unbuffered, so downstream stages see it immediately.
+func (s *SubHunter) writeStdinRecord(record stdinRecord) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	stdout.Write(append(data, '\n'))
+}
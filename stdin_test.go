@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStdinJSONNormalizesAndCaps(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id":1,"name_value":"a.example.com\nb.example.com\nc.example.com"}]`)
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	orig := stdout
+	stdout = &out
+	t.Cleanup(func() { stdout = orig })
+
+	s := testHunter(server.URL)
+	s.perDomainLimit = 2
+	s.processStdinJSON(strings.NewReader(`{"domain": "%.Example.com"}` + "\n"))
+
+	var domains, subdomains []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var record struct {
+			Domain    string `json:"domain"`
+			Subdomain string `json:"subdomain"`
+			Error     string `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("bad record %q: %v", line, err)
+		}
+		if record.Error != "" {
+			t.Fatalf("unexpected error record: %s", record.Error)
+		}
+		domains = append(domains, record.Domain)
+		subdomains = append(subdomains, record.Subdomain)
+	}
+
+	if len(subdomains) != 2 {
+		t.Errorf("got %q, want 2 results (-per-domain-limit)", subdomains)
+	}
+	for _, d := range domains {
+		if d != "example.com" {
+			t.Errorf("record domain = %q, want example.com", d)
+		}
+	}
+	if got := s.domainCounts["example.com"]; got != 3 {
+		t.Errorf("domain count = %d, want 3", got)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import "time"
+
+// streamFlushInterval is how often the writer goroutine flushes a buffered
+// -low-memory output, so results reach the file while a long run goes on.
+const streamFlushInterval = time.Second
+
+// streamQueueSize is how many domains' results may wait for the writer
+// goroutine before workers block.
+const streamQueueSize = 64
+
+// streamedBatch is one domain's results on their way to the writer
+// goroutine. Sending whole batches keeps channel overhead per domain rather
+// than per name.
+type streamedBatch struct {
+	domain string
+	names  []string
+}
+
+// resultWriter funnels streamed results from the workers through a buffered
+// channel to a single goroutine that dedupes and writes them, so workers
+// never wait on the output or each other's writes.
+type resultWriter struct {
+	results chan streamedBatch
+	done    chan struct{}
+	written int
+}
+
+// startResultWriter starts the writer goroutine, deduplicating with seen.
+func (s *SubHunter) startResultWriter(seen seenSet) *resultWriter {
+	w := &resultWriter{
+		results: make(chan streamedBatch, streamQueueSize),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(streamFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case batch, ok := <-w.results:
+				if !ok {
+					s.flushStream()
+					return
+				}
+				for _, name := range batch.names {
+					if seen.add(s.dedupKey(batch.domain, name)) || s.noDedupe {
+						s.emit(name)
+						w.written++
+					}
+				}
+			case <-ticker.C:
+				s.flushStream()
+			}
+		}
+	}()
+	return w
+}
+
+// close waits for every queued result to be written and returns how many
+// were.
+func (w *resultWriter) close() int {
+	close(w.results)
+	<-w.done
+	return w.written
+}
+
+// flushStream flushes the stream writer if it is buffered.
+func (s *SubHunter) flushStream() {
+	if f, ok := s.stream.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+}
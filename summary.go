@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// SummaryStats characterizes a run's result set for the summary.
+type SummaryStats struct {
+	Total           int       `json:"total"`
+	ElapsedSeconds  float64   `json:"elapsed_seconds"`
+	Apexes          int       `json:"apexes,omitempty"`
+	AvgPerApex      float64   `json:"avg_per_apex,omitempty"`
+	MaxDepth        int       `json:"max_depth,omitempty"`
+	LongestName     string    `json:"longest_name,omitempty"`
+	LongestNameSize int       `json:"longest_name_length,omitempty"`
+	CacheHits       int       `json:"cache_hits,omitempty"`
+	CacheMisses     int       `json:"cache_misses,omitempty"`
+	TopCAs          []CACount `json:"top_cas,omitempty"`
+}
+
+// summaryStats computes the summary metrics in a single pass over the final
+// results. Depth counts the labels left of the registrable domain, so
+// "a.b.example.com" has depth 2. Results streamed in -low-memory mode are
+// not retained, so only the total is known for them.
+func (s *SubHunter) summaryStats(subdomains []string, elapsed time.Duration) SummaryStats {
+	stats := SummaryStats{Total: s.totalFound, ElapsedSeconds: elapsed.Seconds()}
+	stats.CacheHits, stats.CacheMisses = s.memCache.stats()
+	if s.caStats {
+		stats.TopCAs = s.topCAs(caStatsTop)
+	}
+
+	apexes := make(map[string]bool)
+	for _, sub := range subdomains {
+		apex := registrableDomain(sub)
+		apexes[apex] = true
+
+		depth := strings.Count(sub, ".") - strings.Count(apex, ".")
+		if depth > stats.MaxDepth {
+			stats.MaxDepth = depth
+		}
+		if len(sub) > stats.LongestNameSize {
+			stats.LongestName = sub
+			stats.LongestNameSize = len(sub)
+		}
+	}
+
+	stats.Apexes = len(apexes)
+	if stats.Apexes > 0 {
+		stats.AvgPerApex = float64(len(subdomains)) / float64(stats.Apexes)
+	}
+	return stats
+}
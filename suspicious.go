@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// confusables maps characters that look like ASCII letters or digits to
+// the character they imitate. It covers the common Cyrillic, Greek and
+// Latin lookalikes rather than the full Unicode confusables table.
+var confusables = map[rune]rune{
+	'а': 'a', 'в': 'b', 'е': 'e', 'һ': 'h', 'і': 'i', 'ј': 'j', 'к': 'k',
+	'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c', 'ѕ': 's', 'т': 't',
+	'у': 'y', 'х': 'x', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'ӏ': 'l',
+	'α': 'a', 'β': 'b', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o',
+	'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w',
+	'ı': 'i', 'ł': 'l', 'ø': 'o', 'ß': 'b', 'ɡ': 'g', 'ɑ': 'a', 'ʏ': 'y',
+	'０': '0', '１': '1', 'ⅰ': 'i', 'ⅼ': 'l',
+}
+
+// skeleton replaces the confusable characters of s with what they imitate
+// and reports whether there were any.
+func skeleton(s string) (string, bool) {
+	found := false
+	mapped := strings.Map(func(r rune) rune {
+		if ascii, ok := confusables[r]; ok {
+			found = true
+			return ascii
+		}
+		return r
+	}, s)
+	return mapped, found
+}
+
+// levenshtein returns the edit distance between a and b in runes.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// typoDistance is the largest edit distance to the brand label that still
+// counts as a near-typo: none for brands of up to three characters, where
+// any short label would be one edit away, one edit for brands up to five
+// characters and two for longer ones.
+func typoDistance(brand string) int {
+	switch n := len([]rune(brand)); {
+	case n <= 3:
+		return 0
+	case n <= 5:
+		return 1
+	}
+	return 2
+}
+
+// isSuspicious reports whether the leftmost label of a subdomain imitates
+// the brand, the first label of its registrable domain: it contains
+// confusable characters or is a near-typo of the brand, like
+// "examp1e.example.com" or "exаmple.example.com" with a Cyrillic "а".
+func isSuspicious(subdomain string) bool {
+	apex := registrableDomain(subdomain)
+	if len(subdomain) <= len(apex) {
+		return false
+	}
+	brand, _, _ := strings.Cut(apex, ".")
+	label, _, _ := strings.Cut(subdomain, ".")
+	if unicodeLabel, err := idna.ToUnicode(label); err == nil {
+		label = unicodeLabel
+	}
+
+	mapped, confusable := skeleton(label)
+	if confusable {
+		return true
+	}
+	if mapped == brand {
+		return false
+	}
+	distance := typoDistance(brand)
+	return distance > 0 && levenshtein(mapped, brand) <= distance
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// syslogTag identifies SubHunter messages in syslog.
+const syslogTag = "subhunter"
+
+// syslogger sends messages to a syslog daemon.
+type syslogger interface {
+	send(msg string) error
+	Close() error
+}
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+var syslogSeverities = map[string]int{
+	"emerg": 0, "alert": 1, "crit": 2, "err": 3,
+	"warning": 4, "notice": 5, "info": 6, "debug": 7,
+}
+
+// syslogPriority combines a facility and severity name into a syslog
+// priority value.
+func syslogPriority(facility, severity string) (int, error) {
+	fac, ok := syslogFacilities[strings.ToLower(facility)]
+	if !ok {
+		return 0, fmt.Errorf("unknown syslog facility %q", facility)
+	}
+	sev, ok := syslogSeverities[strings.ToLower(severity)]
+	if !ok {
+		return 0, fmt.Errorf("unknown syslog severity %q", severity)
+	}
+	return fac<<3 | sev, nil
+}
+
+// splitSyslogAddr splits "udp://host:514" into network and address. A bare
+// "host:514" defaults to UDP; an empty address means the local daemon.
+func splitSyslogAddr(addr string) (network, raddr string) {
+	if addr == "" {
+		return "", ""
+	}
+	if network, raddr, ok := strings.Cut(addr, "://"); ok {
+		return network, raddr
+	}
+	return "udp", addr
+}
+
+// syslogResults sends each subdomain to syslog.
+func (s *SubHunter) syslogResults(subdomains []string) {
+	for _, sub := range subdomains {
+		s.syslogSend("found " + sub)
+	}
+}
+
+// syslogSummary sends the end-of-run summary to syslog.
+func (s *SubHunter) syslogSummary(elapsed time.Duration) {
+	s.syslogSend(fmt.Sprintf("scan complete: %d subdomains in %.2fs", s.totalFound, elapsed.Seconds()))
+}
+
+func (s *SubHunter) syslogSend(msg string) {
+	if s.syslog == nil {
+		return
+	}
+	if err := s.syslog.send(msg); err != nil {
+		s.log("warn", "Syslog write failed", err.Error())
+	}
+}
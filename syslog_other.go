@@ -0,0 +1,44 @@
+//go:build windows || plan9
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// networkSyslog is a minimal RFC 3164 client for platforms without
+// log/syslog. Only remote daemons are supported.
+type networkSyslog struct {
+	conn     net.Conn
+	priority int
+	hostname string
+}
+
+// openSyslog connects to a remote syslog daemon. There is no local daemon to
+// fall back to on this platform, so addr is required.
+func openSyslog(addr string, priority int) (syslogger, error) {
+	if addr == "" {
+		return nil, errors.New("no local syslog on this platform; set -syslog-addr")
+	}
+	network, raddr := splitSyslogAddr(addr)
+	conn, err := net.Dial(network, raddr)
+	if err != nil {
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+	return &networkSyslog{conn: conn, priority: priority, hostname: hostname}, nil
+}
+
+func (l *networkSyslog) send(msg string) error {
+	timestamp := time.Now().Format(time.Stamp)
+	_, err := fmt.Fprintf(l.conn, "<%d>%s %s %s[%d]: %s\n", l.priority, timestamp, l.hostname, syslogTag, os.Getpid(), msg)
+	return err
+}
+
+func (l *networkSyslog) Close() error {
+	return l.conn.Close()
+}
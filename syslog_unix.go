@@ -0,0 +1,29 @@
+//go:build !windows && !plan9
+
+package main
+
+import "log/syslog"
+
+type systemSyslog struct {
+	writer *syslog.Writer
+}
+
+// openSyslog connects to the local syslog daemon, or to a remote one if addr
+// is set.
+func openSyslog(addr string, priority int) (syslogger, error) {
+	network, raddr := splitSyslogAddr(addr)
+	writer, err := syslog.Dial(network, raddr, syslog.Priority(priority), syslogTag)
+	if err != nil {
+		return nil, err
+	}
+	return &systemSyslog{writer: writer}, nil
+}
+
+func (l *systemSyslog) send(msg string) error {
+	_, err := l.writer.Write([]byte(msg))
+	return err
+}
+
+func (l *systemSyslog) Close() error {
+	return l.writer.Close()
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// syncWriter serializes writes to an underlying writer. Each logical line
+// must be written with a single Write call so that output from concurrent
+// workers, including its ANSI sequences, never interleaves mid-line.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// stdout is the shared terminal writer used by everything that can run on a
+// worker goroutine.
+var stdout io.Writer = &syncWriter{w: os.Stdout}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// timelineBarWidth is the length of the longest histogram bar.
+const timelineBarWidth = 40
+
+// TimelineBucket is the number of certificates issued in one month.
+type TimelineBucket struct {
+	Month        string `json:"month"`
+	Certificates int    `json:"certificates"`
+}
+
+// parseNotBefore parses the not_before timestamp format used by crt.sh.
+func parseNotBefore(value string) (time.Time, error) {
+	return time.Parse("2006-01-02T15:04:05", value)
+}
+
+// recordFirstSeen keeps the earliest certificate validity start seen for a
+// subdomain, approximating when it first appeared.
+func (s *SubHunter) recordFirstSeen(subdomain string, result CRTResponse) {
+	issued, err := parseNotBefore(result.NotBefore)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, ok := s.firstSeen[subdomain]; !ok || issued.Before(current) {
+		s.firstSeen[subdomain] = issued
+	}
+}
+
+// recordIssuance counts a matching certificate in its issuance month. Each
+// certificate is counted once even if several domains matched it.
+func (s *SubHunter) recordIssuance(result CRTResponse) {
+	issued, err := parseNotBefore(result.NotBefore)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if result.ID > 0 {
+		if s.timelineSeen[result.ID] {
+			return
+		}
+		s.timelineSeen[result.ID] = true
+	}
+	s.timelineCounts[issued.Format("2006-01")]++
+}
+
+// timelineBuckets returns the recorded months in chronological order.
+func (s *SubHunter) timelineBuckets() []TimelineBucket {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buckets := make([]TimelineBucket, 0, len(s.timelineCounts))
+	for month, count := range s.timelineCounts {
+		buckets = append(buckets, TimelineBucket{Month: month, Certificates: count})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Month < buckets[j].Month
+	})
+	return buckets
+}
+
+// printTimeline shows certificate issuance per month as an ASCII histogram,
+// or as JSON in -json mode.
+func (s *SubHunter) printTimeline() {
+	buckets := s.timelineBuckets()
+
+	if s.jsonOutput {
+		encoder := json.NewEncoder(stdout)
+		if s.pretty {
+			encoder.SetIndent("", "  ")
+		}
+		encoder.Encode(map[string][]TimelineBucket{"timeline": buckets})
+		return
+	}
+	if s.silent {
+		return
+	}
+
+	peak := 0
+	for _, bucket := range buckets {
+		if bucket.Certificates > peak {
+			peak = bucket.Certificates
+		}
+	}
+
+	fmt.Fprintf(stdout, "\n%s%s%s\n", pink, strings.Repeat("━", 60), reset)
+	fmt.Fprintf(stdout, "%s%s[TIMELINE]%s\n", pink, bold, reset)
+	fmt.Fprintf(stdout, "%s%s%s\n", pink, strings.Repeat("━", 60), reset)
+	if len(buckets) == 0 {
+		fmt.Fprintf(stdout, "  No certificate issuance dates found\n")
+	}
+	for _, bucket := range buckets {
+		width := bucket.Certificates * timelineBarWidth / peak
+		if width == 0 {
+			width = 1
+		}
+		fmt.Fprintf(stdout, "  %s %s%s%s %d\n", bucket.Month, pink, strings.Repeat("█", width), reset, bucket.Certificates)
+	}
+}
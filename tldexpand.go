@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// expandTLDs combines a bare brand label with each TLD of a comma-separated
+// -tld-expand list ("com,net,co.uk"), returning the apexes in list order.
+func expandTLDs(base, tlds string) ([]string, error) {
+	base = strings.ToLower(strings.TrimSpace(base))
+	if base == "" || strings.Contains(base, ".") {
+		return nil, fmt.Errorf("-tld-expand needs -d to be a bare name without dots, like \"example\"")
+	}
+
+	var apexes []string
+	seen := make(map[string]bool)
+	for _, tld := range strings.Split(tlds, ",") {
+		tld = strings.Trim(strings.ToLower(strings.TrimSpace(tld)), ".")
+		if tld == "" {
+			continue
+		}
+		for _, c := range tld {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '.') {
+				return nil, fmt.Errorf("invalid TLD %q in -tld-expand", tld)
+			}
+		}
+		if apex := base + "." + tld; !seen[apex] {
+			seen[apex] = true
+			apexes = append(apexes, apex)
+		}
+	}
+	if len(apexes) == 0 {
+		return nil, fmt.Errorf("-tld-expand lists no TLDs")
+	}
+	return apexes, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// tlsVersions are the values accepted by -tls-min and -tls-max.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func parseTLSVersion(value string) (uint16, error) {
+	version, ok := tlsVersions[value]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q (expected 1.0, 1.1, 1.2 or 1.3)", value)
+	}
+	return version, nil
+}
+
+// parseCipherSuites reads a comma-separated list of cipher suite names as
+// printed by Go, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256.
+func parseCipherSuites(list string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, suites := range [][]*tls.CipherSuite{tls.CipherSuites(), tls.InsecureCipherSuites()} {
+		for _, suite := range suites {
+			known[suite.Name] = suite.ID
+		}
+	}
+
+	var ids []uint16
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("-tls-ciphers lists no cipher suites")
+	}
+	return ids, nil
+}
+
+// buildTLSConfig returns the client TLS settings for -tls-min, -tls-max and
+// -tls-ciphers, or nil if none is given. Go offers the cipher suites in its
+// own order and does not make TLS 1.3 suites configurable, so -tls-ciphers
+// only changes which suites are offered for TLS 1.2 and older.
+func buildTLSConfig(minVersion, maxVersion, ciphers string) (*tls.Config, error) {
+	if minVersion == "" && maxVersion == "" && ciphers == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{}
+	var err error
+	if minVersion != "" {
+		if config.MinVersion, err = parseTLSVersion(minVersion); err != nil {
+			return nil, fmt.Errorf("-tls-min: %v", err)
+		}
+	}
+	if maxVersion != "" {
+		if config.MaxVersion, err = parseTLSVersion(maxVersion); err != nil {
+			return nil, fmt.Errorf("-tls-max: %v", err)
+		}
+	}
+	if config.MinVersion != 0 && config.MaxVersion != 0 && config.MinVersion > config.MaxVersion {
+		return nil, fmt.Errorf("-tls-min %s is above -tls-max %s", minVersion, maxVersion)
+	}
+	if ciphers != "" {
+		if config.MinVersion == tls.VersionTLS13 {
+			return nil, fmt.Errorf("-tls-ciphers has no effect with -tls-min 1.3")
+		}
+		if config.CipherSuites, err = parseCipherSuites(ciphers); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// setTLSConfig makes the HTTP client use config, keeping the other
+// defaults of Go's transport.
+func (s *SubHunter) setTLSConfig(config *tls.Config) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	s.client.Transport = transport
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// validateFile filters a list of collected subdomains down to valid DNS
+// names. Entries are read like dedupe's, so annotated and unicode SubHunter
+// output is accepted. Rejected entries are printed to stderr with the
+// reason.
+func (s *SubHunter) validateFile(filename string, outputs []string) error {
+	lines, err := readLines(filename)
+	if err != nil {
+		return err
+	}
+
+	var valid []string
+	rejected := make(map[string]int)
+	for _, line := range lines {
+		subdomain := normalizeListEntry(line)
+		if subdomain == "" {
+			continue // comment line
+		}
+		if problem := subdomainProblem(subdomain); problem != "" {
+			rejected[problem]++
+			fmt.Fprintf(os.Stderr, "%s\t%s\n", line, problem)
+			continue
+		}
+		valid = append(valid, subdomain)
+	}
+
+	var reasons []string
+	total := 0
+	for reason, count := range rejected {
+		reasons = append(reasons, fmt.Sprintf("%s: %d", reason, count))
+		total += count
+	}
+	sort.Strings(reasons)
+
+	s.log("info", fmt.Sprintf("Validated %d entries from", len(lines)), filename)
+	if total > 0 {
+		s.log("warn", fmt.Sprintf("Rejected %d invalid entries", total), strings.Join(reasons, ", "))
+	}
+
+	if len(outputs) > 0 {
+		return s.saveToFiles(valid, outputs)
+	}
+	if s.jsonOutput {
+		return s.writeResults(stdout, valid)
+	}
+	for _, sub := range valid {
+		s.printResult(sub)
+	}
+	return nil
+}
+
+// runValidate implements the validate command.
+func runValidate(args []string) {
+	fs := newFlagSet("validate", "validate [flags] file")
+	silent := fs.Bool("silent", false, "silent mode (only results)")
+	format := registerFormatFlags(fs)
+	destination := registerDestinationFlags(fs)
+	files := parseInterspersed(fs, args)
+
+	printBanner(*silent)
+
+	if len(files) != 1 {
+		fmt.Printf("%s[ERR]%s validate requires exactly one input file\n\n", pink, reset)
+		os.Exit(1)
+	}
+
+	hunter := NewSubHunter(defaultTimeout, 1, *silent)
+	exitOnError(format.apply(hunter))
+	outputs, err := destination.apply(hunter)
+	exitOnError(err)
+
+	if err := hunter.validateFile(files[0], outputs); err != nil {
+		hunter.log("error", "Validation failed", err.Error())
+		os.Exit(1)
+	}
+}
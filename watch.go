@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// watch re-enumerates a single domain every interval until the run is
+// interrupted, printing only the subdomains that were not in any earlier
+// cycle, each with the time it was found. A heartbeat is logged every
+// cycle, even when nothing changed. The in-memory result cache is turned
+// off, since it would answer every cycle after the first.
+func (s *SubHunter) watch(domain string, interval time.Duration) {
+	s.memCache = nil
+
+	known := make(mapSet)
+	for _, sub := range s.processDomain(domain, false) {
+		known.add(sub)
+	}
+	s.log("info", fmt.Sprintf("Watching with %d known subdomains, checking every %s:", len(known), interval), domain)
+
+	for cycle := 1; ; cycle++ {
+		if !s.wait(interval) {
+			s.log("info", fmt.Sprintf("Stopped watching after %d cycles with %d known subdomains", cycle-1, len(known)), "")
+			return
+		}
+
+		fresh := 0
+		for _, sub := range s.processDomain(domain, false) {
+			if !known.add(sub) {
+				continue
+			}
+			fresh++
+			for _, line := range s.formatResult(sub) {
+				s.printLine(time.Now().UTC().Format(time.RFC3339) + " " + line)
+			}
+		}
+		s.log("info", fmt.Sprintf("Watch cycle %d: %d new, %d known", cycle, fresh, len(known)), domain)
+	}
+}
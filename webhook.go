@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// webhookPayload is posted to -webhook. text and content carry a readable
+// message for Slack and Discord respectively; the other fields are for
+// generic receivers.
+type webhookPayload struct {
+	Text       string   `json:"text"`
+	Content    string   `json:"content"`
+	Domain     string   `json:"domain"`
+	Count      int      `json:"count"`
+	Subdomains []string `json:"subdomains"`
+}
+
+// loadKnown reads the -known list of previously seen subdomains.
+func loadKnown(filename string) (map[string]bool, error) {
+	lines, err := readLines(filename)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(lines))
+	for _, line := range lines {
+		known[normalizeSubdomain(line)] = true
+	}
+	return known, nil
+}
+
+// newSubdomains returns the subdomains missing from known, in order.
+func newSubdomains(subdomains []string, known map[string]bool) []string {
+	var fresh []string
+	for _, sub := range subdomains {
+		if !known[sub] {
+			fresh = append(fresh, sub)
+		}
+	}
+	return fresh
+}
+
+// webhookTextLimit is the longest message Discord accepts as content; it
+// rejects longer ones outright. Slack's limit is higher, but the same text
+// serves both.
+const webhookTextLimit = 2000
+
+// webhookText returns the readable message for the new subdomains, listing
+// as many as fit in webhookTextLimit characters and counting the rest.
+// The payload's subdomains field always has the full list.
+func webhookText(domain string, fresh []string) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "SubHunter found %d new subdomains for %s:", len(fresh), domain)
+	size := utf8.RuneCountInString(text.String())
+	// room kept for the "and N more" line unless the last name fits
+	reserve := utf8.RuneCountInString(fmt.Sprintf("\n…and %d more", len(fresh)))
+
+	for i, sub := range fresh {
+		room := webhookTextLimit - size
+		if i < len(fresh)-1 {
+			room -= reserve
+		}
+		line := "\n" + sub
+		if n := utf8.RuneCountInString(line); n <= room {
+			text.WriteString(line)
+			size += n
+			continue
+		}
+		fmt.Fprintf(&text, "\n…and %d more", len(fresh)-i)
+		break
+	}
+	return text.String()
+}
+
+// notifyWebhook posts the new subdomains of a run to url, retrying failed
+// deliveries with the usual backoff.
+func (s *SubHunter) notifyWebhook(url, domain string, fresh []string) error {
+	text := webhookText(domain, fresh)
+	body, err := json.Marshal(webhookPayload{
+		Text:       text,
+		Content:    text,
+		Domain:     domain,
+		Count:      len(fresh),
+		Subdomains: fresh,
+	})
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= s.maxRetries; attempt++ {
+		if attempt > 1 {
+			if !s.takeRetry() {
+				break
+			}
+			s.log("retry", fmt.Sprintf("Webhook attempt %d/%d for", attempt, s.maxRetries), url)
+			if !s.wait(s.backoff(attempt)) {
+				return fmt.Errorf("webhook %w, last error: %v", ErrInterrupted, lastErr)
+			}
+		}
+
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, _, err := s.do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			lastErr = ErrHTTPStatus{resp.StatusCode}
+			continue
+		}
+
+		s.log("success", fmt.Sprintf("Sent %d new subdomains to", len(fresh)), url)
+		return nil
+	}
+	return fmt.Errorf("webhook failed: %v", lastErr)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestWebhookTextLimit(t *testing.T) {
+	var payload webhookPayload
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		// like Discord, refuse an over-long message
+		if utf8.RuneCountInString(payload.Content) > webhookTextLimit {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer receiver.Close()
+
+	var fresh []string
+	for i := 0; i < 500; i++ {
+		fresh = append(fresh, fmt.Sprintf("host-%03d.example.com", i))
+	}
+
+	s := testHunter(receiver.URL)
+	if err := s.notifyWebhook(receiver.URL, "example.com", fresh); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := utf8.RuneCountInString(payload.Text); n > webhookTextLimit {
+		t.Errorf("text is %d characters, want at most %d", n, webhookTextLimit)
+	}
+	lines := strings.Split(payload.Content, "\n")
+	listed := len(lines) - 2 // heading and "…and N more"
+	if want := fmt.Sprintf("…and %d more", len(fresh)-listed); lines[len(lines)-1] != want {
+		t.Errorf("last line = %q, want %q", lines[len(lines)-1], want)
+	}
+	if payload.Count != len(fresh) || len(payload.Subdomains) != len(fresh) {
+		t.Errorf("payload has count %d and %d subdomains, want the full %d", payload.Count, len(payload.Subdomains), len(fresh))
+	}
+}
+
+func TestWebhookTextShortList(t *testing.T) {
+	fresh := []string{"a.example.com", "b.example.com"}
+	want := "SubHunter found 2 new subdomains for example.com:\na.example.com\nb.example.com"
+	if got := webhookText("example.com", fresh); got != want {
+		t.Errorf("webhookText = %q, want %q", got, want)
+	}
+}
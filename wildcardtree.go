@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WildcardGroup is a wildcard certificate name with the concrete subdomains
+// it covers. A wildcard only matches a single label, so *.example.com
+// covers www.example.com but not a.b.example.com.
+type WildcardGroup struct {
+	Wildcard string   `json:"wildcard"`
+	Hosts    []string `json:"hosts"`
+}
+
+// recordWildcard notes that a certificate names *.base.
+func (s *SubHunter) recordWildcard(base string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.wildcards == nil {
+		s.wildcards = make(map[string]bool)
+	}
+	s.wildcards[base] = true
+}
+
+// wildcardGroups maps the subdomains onto the wildcard names seen during
+// extraction, sorted by wildcard.
+func (s *SubHunter) wildcardGroups(subdomains []string) []WildcardGroup {
+	hosts := make(map[string][]string, len(s.wildcards))
+	for _, sub := range subdomains {
+		if _, parent, ok := strings.Cut(sub, "."); ok && s.wildcards[parent] {
+			hosts[parent] = append(hosts[parent], sub)
+		}
+	}
+
+	groups := make([]WildcardGroup, 0, len(s.wildcards))
+	for base := range s.wildcards {
+		covered := hosts[base]
+		if covered == nil {
+			covered = []string{} // "hosts": [] rather than null in JSON
+		}
+		groups = append(groups, WildcardGroup{Wildcard: "*." + base, Hosts: covered})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Wildcard < groups[j].Wildcard
+	})
+	return groups
+}
+
+// printWildcardTree prints each wildcard certificate name with the concrete
+// subdomains nested under it.
+func (s *SubHunter) printWildcardTree(subdomains []string) {
+	groups := s.wildcardGroups(subdomains)
+
+	if s.jsonOutput {
+		encoder := json.NewEncoder(stdout)
+		if s.pretty {
+			encoder.SetIndent("", "  ")
+		}
+		encoder.Encode(map[string][]WildcardGroup{"wildcard_tree": groups})
+		return
+	}
+	if s.silent {
+		return
+	}
+
+	fmt.Fprintf(stdout, "\n%s%s%s\n", pink, strings.Repeat("━", 60), reset)
+	fmt.Fprintf(stdout, "%s%s[WILDCARD TREE]%s\n", pink, bold, reset)
+	fmt.Fprintf(stdout, "%s%s%s\n", pink, strings.Repeat("━", 60), reset)
+	if len(groups) == 0 {
+		fmt.Fprintf(stdout, "  No wildcard certificates found\n")
+	}
+	for _, group := range groups {
+		fmt.Fprintf(stdout, "  %s%s%s (%d)\n", pink, group.Wildcard, reset, len(group.Hosts))
+		for _, host := range group.Hosts {
+			fmt.Fprintf(stdout, "    └─ %s\n", host)
+		}
+	}
+}